@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Access log util constants
+const (
+	cnfAccessLogSampleRateKey = "server.access_log.sample_rate"
+	accessLogMsg              = "%v | %d bytes | user=%s"
+	invalidSampleRateErrMsg   = "Invalid value %v for %s. The value should be between 0 and 1"
+)
+
+// InvalidSampleRateError represents an error when the sample rate is not between 0 and 1
+type InvalidSampleRateError float64
+
+// Error returns the formatted InvalidSampleRateError
+func (isr InvalidSampleRateError) Error() string {
+	return fmt.Sprintf(invalidSampleRateErrMsg, float64(isr), cnfAccessLogSampleRateKey)
+}
+
+// AccessLogCnf represents the access log configuration
+// SkipPaths are the request paths which are never logged, for example health checks
+// SampleRate is the fraction of the successful requests which are logged, requests which fail with
+// a 4xx or 5xx status code are always logged, zero means all the requests are logged
+type AccessLogCnf struct {
+	SkipPaths  []string `yaml:"skip_paths" mapstructure:"skip_paths"`
+	SampleRate float64  `yaml:"sample_rate" mapstructure:"sample_rate"`
+}
+
+// Validate checks if the values in the AccessLogCnf are valid
+func (al *AccessLogCnf) Validate() error {
+	if al.SampleRate < 0 || al.SampleRate > 1 {
+		return InvalidSampleRateError(al.SampleRate)
+	}
+	return nil
+}
+
+// sampled returns true if a successful request should be logged
+func (al *AccessLogCnf) sampled() bool {
+	return al.SampleRate == 0 || al.SampleRate == 1 || rand.Float64() < al.SampleRate
+}
+
+// AccessLog is a gin middleware which logs every request using the LogFormatter
+// The log message contains the latency, the response size and the authenticated user along with the
+// status code, requester IP, method, uri and request id rendered by the LogFormatter
+// Requests are logged at info level, 4xx responses at warn level and 5xx responses at error level
+func AccessLog(cnf AccessLogCnf) gin.HandlerFunc {
+	skip := make(map[string]bool, len(cnf.SkipPaths))
+	for _, path := range cnf.SkipPaths {
+		skip[path] = true
+	}
+
+	return func(ctx *gin.Context) {
+		if skip[ctx.Request.URL.Path] {
+			ctx.Next()
+			return
+		}
+
+		start := time.Now()
+		ctx.Next()
+		latency := time.Since(start)
+
+		status := ctx.Writer.Status()
+		if status < http.StatusBadRequest && !cnf.sampled() {
+			return
+		}
+
+		size := ctx.Writer.Size()
+		if size < 0 {
+			size = 0
+		}
+		user := ctx.GetString(UsernameKey)
+		if user == "" {
+			user = "-"
+		}
+
+		log := LogFormatter{
+			Request:    ctx.Request,
+			StatusCode: status,
+			Msg:        fmt.Sprintf(accessLogMsg, latency, size, user),
+		}
+		switch {
+		case status >= http.StatusInternalServerError:
+			log.Error().Println(log.Out)
+		case status >= http.StatusBadRequest:
+			log.Warn().Println(log.Out)
+		default:
+			log.Info().Println(log.Out)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// captureStdout returns everything which is written to stdout while fn runs
+func captureStdout(t *testing.T, fn func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() {
+		os.Stdout = stdout
+	}()
+
+	out := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		_, _ = io.Copy(&buf, r)
+		out <- buf.String()
+	}()
+
+	fn()
+	w.Close()
+	return <-out
+}
+
+func TestAccessLog(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	engine := gin.New()
+	engine.Use(RequestID(), AccessLog(AccessLogCnf{SkipPaths: []string{"/healthz"}}))
+	engine.GET("/users", func(ctx *gin.Context) {
+		ctx.Set(UsernameKey, "alice")
+		ctx.String(http.StatusOK, "hello")
+	})
+	engine.GET("/healthz", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "ok")
+	})
+
+	out := captureStdout(t, func() {
+		req := httptest.NewRequest(http.MethodGet, "/users", nil)
+		req.Header.Set(RequestIDHeader, "req-1")
+		engine.ServeHTTP(httptest.NewRecorder(), req)
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	})
+
+	for _, expected := range []string{"[INFO ]", "/users", "5 bytes", "user=alice", "request_id=req-1"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Test Failed!, expected %q in: %s", expected, out)
+		}
+	}
+	if strings.Contains(out, "/healthz") {
+		t.Errorf("Test Failed!, expected /healthz to be skipped, got: %s", out)
+	}
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	engine := gin.New()
+	engine.Use(AccessLog(AccessLogCnf{SampleRate: 0.000001}))
+	engine.GET("/ok", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "ok")
+	})
+	engine.GET("/fail", func(ctx *gin.Context) {
+		ctx.String(http.StatusInternalServerError, "fail")
+	})
+
+	out := captureStdout(t, func() {
+		for i := 0; i < 10; i++ {
+			engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+		}
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	})
+
+	if strings.Contains(out, "/ok") {
+		t.Errorf("Test Failed!, expected the successful requests to be sampled out, got: %s", out)
+	}
+	if !strings.Contains(out, "[ERROR]") || !strings.Contains(out, "/fail") {
+		t.Errorf("Test Failed!, expected the failed request to be logged at error level, got: %s", out)
+	}
+}
+
+func TestAccessLogCnfValidate(t *testing.T) {
+	cnf := AccessLogCnf{SampleRate: 1.5}
+	if _, ok := cnf.Validate().(InvalidSampleRateError); !ok {
+		t.Error("Test Failed!, expected an InvalidSampleRateError")
+	}
+}
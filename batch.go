@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Batch util constants
+const (
+	defaultBatchConcurrency = 10
+
+	batchRequestErrMsg = "Request %d to '%s' failed : %v"
+	batchErrMsg        = "%d requests of the batch failed : %s"
+	batchDoneMsg       = "Completed batch of %d requests with %d failures"
+)
+
+// BatchRequestError represents an error of a single request of a batch
+type BatchRequestError struct {
+	Index int
+	Url   string
+	Err   error
+}
+
+// Error returns the formatted BatchRequestError
+func (bre BatchRequestError) Error() string {
+	return fmt.Sprintf(batchRequestErrMsg, bre.Index, bre.Url, bre.Err)
+}
+
+// Unwrap returns the underlying error
+func (bre BatchRequestError) Unwrap() error {
+	return bre.Err
+}
+
+// BatchError represents the errors of the failed requests of a batch in the order of the requests
+type BatchError []BatchRequestError
+
+// Error returns the formatted BatchError
+func (be BatchError) Error() string {
+	msgs := make([]string, len(be))
+	for i, err := range be {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf(batchErrMsg, len(be), strings.Join(msgs, "; "))
+}
+
+// BatchResult represents the outcome of a single request of a batch
+// Err is set if the request could not be made, was cancelled or, when FailOnStatusError is set,
+// the remote server responded with a non 2xx status code
+type BatchResult struct {
+	Result Result
+	Err    error
+}
+
+// Batch makes the Requests using a bounded pool of workers
+// Concurrency is the number of requests which are made in parallel and defaults to 10
+// If FailFast is set the remaining requests are cancelled after the first failure
+// Ctx is optional and cancels the batch, it is used as the context of the requests which do not have their own Ctx
+// Requests with their own Ctx are not interrupted when the batch is cancelled but are skipped if they did not start yet
+type Batch struct {
+	Requests          []Request
+	Concurrency       int
+	FailFast          bool
+	FailOnStatusError bool
+	Ctx               context.Context
+}
+
+// Do makes all the requests of the batch and returns the results in the order of the requests
+// The method returns a BatchError with the errors of all the failed requests
+func (b *Batch) Do() ([]BatchResult, error) {
+	parent := b.Ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	concurrency := b.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+	if concurrency > len(b.Requests) {
+		concurrency = len(b.Requests)
+	}
+
+	results := make([]BatchResult, len(b.Requests))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = b.do(ctx, b.Requests[i])
+				if results[i].Err != nil && b.FailFast {
+					cancel()
+				}
+			}
+		}()
+	}
+
+	for i := range b.Requests {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var errs BatchError
+	for i, result := range results {
+		if result.Err != nil {
+			errs = append(errs, BatchRequestError{Index: i, Url: b.Requests[i].Url, Err: result.Err})
+		}
+	}
+
+	log := LogFormatter{Msg: fmt.Sprintf(batchDoneMsg, len(b.Requests), len(errs))}
+	log.Debug().Println(log.Out)
+
+	if errs != nil {
+		return results, errs
+	}
+	return results, nil
+}
+
+// do makes a single request of the batch unless the batch was cancelled
+func (b *Batch) do(ctx context.Context, r Request) BatchResult {
+	if err := ctx.Err(); err != nil {
+		return BatchResult{Err: err}
+	}
+	if r.Ctx == nil {
+		r.Ctx = ctx
+	}
+
+	if err := r.NewRequest(); err != nil {
+		return BatchResult{Err: err}
+	}
+	if err := r.HttpRequest(); err != nil {
+		return BatchResult{Result: r.Result, Err: err}
+	}
+	if b.FailOnStatusError {
+		if err := r.Result.StatusError(); err != nil {
+			return BatchResult{Result: r.Result, Err: err}
+		}
+	}
+	return BatchResult{Result: r.Result}
+}
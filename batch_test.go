@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBatch(t *testing.T) {
+	var running, maxRunning int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		if r.URL.Query().Get("id") == "3" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(r.URL.Query().Get("id")))
+	}))
+	defer server.Close()
+
+	b := Batch{Concurrency: 3, FailOnStatusError: true}
+	for i := 0; i < 10; i++ {
+		b.Requests = append(b.Requests, Request{Url: server.URL + "?id=" + strconv.Itoa(i), Method: http.MethodGet})
+	}
+
+	results, err := b.Do()
+	if len(results) != 10 {
+		t.Fatalf("Test Failed!, expected: %v results, got: %v", 10, len(results))
+	}
+	for i, result := range results {
+		if i == 3 {
+			continue
+		}
+		if result.Err != nil || string(result.Result.Body) != strconv.Itoa(i) {
+			t.Errorf("Test Failed!, expected: %v, got: %q, %v", i, result.Result.Body, result.Err)
+		}
+	}
+
+	var be BatchError
+	if !errors.As(err, &be) || len(be) != 1 || be[0].Index != 3 {
+		t.Fatalf("Test Failed!, expected a BatchError for request 3, got: %v", err)
+	}
+	var se HTTPStatusError
+	if !errors.As(be[0], &se) || se.StatusCode != http.StatusNotFound {
+		t.Errorf("Test Failed!, expected: HTTPStatusError, got: %v", be[0].Err)
+	}
+	if maxRunning > 3 {
+		t.Errorf("Test Failed!, expected at most %v concurrent requests, got: %v", 3, maxRunning)
+	}
+}
+
+func TestBatchFailFast(t *testing.T) {
+	var made int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&made, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	b := Batch{Concurrency: 1, FailFast: true, FailOnStatusError: true}
+	for i := 0; i < 5; i++ {
+		b.Requests = append(b.Requests, Request{Url: server.URL, Method: http.MethodGet})
+	}
+
+	results, err := b.Do()
+	if err == nil {
+		t.Fatal("Test Failed!, expected a BatchError")
+	}
+	if made != 1 {
+		t.Errorf("Test Failed!, expected: %v request, got: %v", 1, made)
+	}
+	for _, result := range results[1:] {
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("Test Failed!, expected: %v, got: %v", context.Canceled, result.Err)
+		}
+	}
+}
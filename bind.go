@@ -0,0 +1,224 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// Binding util constants
+const (
+	requestBodyTooLargeErrMsg = "Request body is larger than the limit of %d bytes"
+	emptyBodyMsg              = "Request body is empty"
+	unknownFieldMsg           = "Unknown field %s"
+	invalidFieldTypeMsg       = "Field '%s' must be of type %s"
+	invalidJSONMsg            = "Invalid JSON at offset %d : %v"
+	trailingDataMsg           = "Request body must contain a single JSON value"
+	fieldValidationMsg        = "Field '%s' failed on the '%s' validation"
+	fieldValidationParamMsg   = "Field '%s' failed on the '%s=%s' validation"
+	bindFailedMsg             = "Request body validation failed"
+	unknownFieldPrefix        = "json: unknown field "
+)
+
+var (
+	// strictValidator validates the objects bound by BindStrictJSON using the binding struct tags, it is separate
+	// from the validator of gin so that the json tag names are only used in the errors of BindStrictJSON
+	strictValidator = newStrictValidator()
+)
+
+// RequestBodyTooLargeError represents an error when the request body is larger than the limit
+type RequestBodyTooLargeError int64
+
+// Error returns the formatted RequestBodyTooLargeError
+func (rbt RequestBodyTooLargeError) Error() string {
+	return fmt.Sprintf(requestBodyTooLargeErrMsg, int64(rbt))
+}
+
+// limitedBody is a request body read through http.MaxBytesReader which fails with a RequestBodyTooLargeError
+// when more than limit bytes are read
+type limitedBody struct {
+	body  io.ReadCloser
+	limit int64
+	read  int64
+}
+
+// Read reads from the body until the limit is exceeded
+func (lb *limitedBody) Read(p []byte) (int, error) {
+	n, err := lb.body.Read(p)
+	lb.read += int64(n)
+	// the MaxBytesReader only fails after the limit is read when the body is larger than the limit
+	if err != nil && err != io.EOF && lb.read >= lb.limit {
+		err = RequestBodyTooLargeError(lb.limit)
+	}
+	return n, err
+}
+
+// Close closes the body
+func (lb *limitedBody) Close() error {
+	return lb.body.Close()
+}
+
+// AbortIfBodyTooLarge aborts the request with 413 Request Entity Too Large if the error was returned because
+// the request body is larger than the limit of the BodyLimit middleware
+// Handlers which read the body without BindStrictJSON, for example using ShouldBindJSON or GetRawData,
+// call it with the error of the read and return if it returns true
+func AbortIfBodyTooLarge(ctx *gin.Context, err error) bool {
+	var tooLarge RequestBodyTooLargeError
+	if !errors.As(err, &tooLarge) {
+		return false
+	}
+	abortTooLarge(ctx)
+	return true
+}
+
+// abortTooLarge aborts the request with 413 Request Entity Too Large
+func abortTooLarge(ctx *gin.Context) {
+	ctx.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, ErrResponse{Error: RequestEntityTooLargeMsg})
+	log := LogFormatter{Request: ctx.Request, StatusCode: http.StatusRequestEntityTooLarge, Msg: RequestEntityTooLargeMsg}
+	log.Info().Println(log.Out)
+}
+
+// BodyLimit is a gin middleware which limits the size of the request bodies to maxBytes
+// Requests with a larger Content-Length are rejected with 413 Request Entity Too Large, reading more than
+// maxBytes from a body without a Content-Length fails with a RequestBodyTooLargeError
+// BindStrictJSON responds with 413 Request Entity Too Large to this error, other handlers use AbortIfBodyTooLarge
+func BodyLimit(maxBytes int64) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.Request.ContentLength > maxBytes {
+			abortTooLarge(ctx)
+			return
+		}
+		if ctx.Request.Body != nil && ctx.Request.Body != http.NoBody {
+			ctx.Request.Body = &limitedBody{body: http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxBytes), limit: maxBytes}
+		}
+		ctx.Next()
+	}
+}
+
+// decodeErrMsg returns a message describing the JSON decode error
+func decodeErrMsg(err error) string {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case err == io.EOF:
+		return emptyBodyMsg
+	case errors.As(err, &syntaxErr):
+		return fmt.Sprintf(invalidJSONMsg, syntaxErr.Offset, syntaxErr)
+	case errors.As(err, &typeErr):
+		return fmt.Sprintf(invalidFieldTypeMsg, typeErr.Field, typeErr.Type)
+	case strings.HasPrefix(err.Error(), unknownFieldPrefix):
+		return fmt.Sprintf(unknownFieldMsg, strings.TrimPrefix(err.Error(), unknownFieldPrefix))
+	}
+	return err.Error()
+}
+
+// jsonTagName returns the name of the field in the JSON body so that the validation errors use the same
+// names as the decode errors, fields which are not decoded are named after the struct field
+func jsonTagName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+// newStrictValidator returns a validator which reads the binding struct tags and reports the json tag names
+func newStrictValidator() *validator.Validate {
+	v := validator.New()
+	v.SetTagName("binding")
+	v.RegisterTagNameFunc(jsonTagName)
+	return v
+}
+
+// validateStruct validates obj if it is a struct or a pointer to a struct
+func validateStruct(obj interface{}) error {
+	v := reflect.ValueOf(obj)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	return strictValidator.Struct(obj)
+}
+
+// validationMessages returns a message for every field which failed the struct tag validation
+func validationMessages(err error) []string {
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return []string{err.Error()}
+	}
+	msgs := make([]string, 0, len(fieldErrs))
+	for _, fe := range fieldErrs {
+		// the namespace starts with the name of the struct which is not useful to the client
+		field := fe.Namespace()
+		if i := strings.IndexByte(field, '.'); i != -1 {
+			field = field[i+1:]
+		}
+		if fe.Param() != "" {
+			msgs = append(msgs, fmt.Sprintf(fieldValidationParamMsg, field, fe.Tag(), fe.Param()))
+		} else {
+			msgs = append(msgs, fmt.Sprintf(fieldValidationMsg, field, fe.Tag()))
+		}
+	}
+	return msgs
+}
+
+// BindStrictJSON decodes the JSON request body into obj and validates it using the binding struct tags
+// The validation errors name the fields by their json tag names, the validator of gin is not used
+// Unknown fields and trailing data are rejected, if the body cannot be decoded or is not valid the request is
+// aborted with 400 Bad Request and a ValidationResult listing every error, a body larger than the limit
+// of the BodyLimit middleware is aborted with 413 Request Entity Too Large
+// The method returns true if obj was bound successfully
+func BindStrictJSON(ctx *gin.Context, obj interface{}) bool {
+	var msgs []string
+
+	body := ctx.Request.Body
+	if body == nil {
+		body = http.NoBody
+	}
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(obj)
+	trailing := false
+	if err == nil {
+		// a single JSON value must be followed by the end of the body
+		if _, err = decoder.Token(); err == io.EOF {
+			err = nil
+		} else {
+			trailing = true
+		}
+	}
+
+	var tooLarge RequestBodyTooLargeError
+	switch {
+	case errors.As(err, &tooLarge):
+		abortTooLarge(ctx)
+		return false
+	case trailing:
+		msgs = []string{trailingDataMsg}
+	case err != nil:
+		msgs = []string{decodeErrMsg(err)}
+	default:
+		if err := validateStruct(obj); err != nil {
+			msgs = validationMessages(err)
+		}
+	}
+
+	if len(msgs) == 0 {
+		return true
+	}
+
+	ctx.AbortWithStatusJSON(http.StatusBadRequest, ValidationResult{Valid: false, Messages: msgs})
+	log := LogFormatter{Request: ctx.Request, StatusCode: http.StatusBadRequest, Msg: bindFailedMsg + " : " + strings.Join(msgs, ", ")}
+	log.Info().Println(log.Out)
+	return false
+}
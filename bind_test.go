@@ -0,0 +1,169 @@
+package utils
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type bindTestUser struct {
+	Name  string `json:"name" binding:"required"`
+	Email string `json:"email" binding:"required,email"`
+	Age   int    `json:"age" binding:"min=18"`
+}
+
+// newBindEngine returns a gin engine with the body limit middleware and a route which binds a bindTestUser
+func newBindEngine(maxBytes int64) *gin.Engine {
+	gin.SetMode(gin.ReleaseMode)
+	engine := gin.New()
+	engine.Use(BodyLimit(maxBytes))
+	engine.POST("/users", func(ctx *gin.Context) {
+		var user bindTestUser
+		if !BindStrictJSON(ctx, &user) {
+			return
+		}
+		ctx.JSON(http.StatusCreated, user)
+	})
+	return engine
+}
+
+// postBody makes a POST request with the body, a negative contentLength sends the body chunked
+func postBody(engine *gin.Engine, body string, contentLength int64) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	if contentLength < 0 {
+		req.Body = ioutil.NopCloser(strings.NewReader(body))
+	}
+	req.ContentLength = contentLength
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestBodyLimit(t *testing.T) {
+	engine := newBindEngine(64)
+	body := `{"name":"` + strings.Repeat("a", 100) + `","email":"a@b.c","age":20}`
+
+	for _, contentLength := range []int64{int64(len(body)), -1} {
+		w := postBody(engine, body, contentLength)
+		var resp ErrResponse
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Test Failed!, expected: %v, got: %v", http.StatusRequestEntityTooLarge, w.Code)
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Error != RequestEntityTooLargeMsg {
+			t.Errorf("Test Failed!, expected: %v, got: %v", RequestEntityTooLargeMsg, w.Body.String())
+		}
+	}
+}
+
+func TestBindStrictJSON(t *testing.T) {
+	engine := newBindEngine(1024)
+
+	tests := []struct {
+		name     string
+		body     string
+		expected []string
+	}{
+		{"unknown field", `{"name":"a","email":"a@b.c","age":20,"admin":true}`, []string{`Unknown field "admin"`}},
+		{"invalid type", `{"name":"a","email":"a@b.c","age":"20"}`, []string{"Field 'age' must be of type int"}},
+		{"trailing data", `{"name":"a","email":"a@b.c","age":20}{}`, []string{trailingDataMsg}},
+		{"empty body", ``, []string{emptyBodyMsg}},
+		{"validation", `{"email":"invalid","age":10}`, []string{
+			"Field 'name' failed on the 'required' validation",
+			"Field 'email' failed on the 'email' validation",
+			"Field 'age' failed on the 'min=18' validation",
+		}},
+	}
+
+	for _, test := range tests {
+		w := postBody(engine, test.body, int64(len(test.body)))
+		var result ValidationResult
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Test Failed!, %s expected: %v, got: %v", test.name, http.StatusBadRequest, w.Code)
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil || result.Valid {
+			t.Errorf("Test Failed!, %s unexpected response: %v", test.name, w.Body.String())
+		}
+		if strings.Join(result.Messages, "\n") != strings.Join(test.expected, "\n") {
+			t.Errorf("Test Failed!, %s expected: %v, got: %v", test.name, test.expected, result.Messages)
+		}
+	}
+
+	w := postBody(engine, `{"name":"a","email":"a@b.c","age":20}`, -1)
+	var user bindTestUser
+	if err := json.Unmarshal(w.Body.Bytes(), &user); err != nil || w.Code != http.StatusCreated || user.Age != 20 {
+		t.Errorf("Test Failed!, expected: %v, got: %v %v", http.StatusCreated, w.Code, w.Body.String())
+	}
+}
+
+func TestAbortIfBodyTooLarge(t *testing.T) {
+	engine := newBindEngine(16)
+	engine.POST("/bind", func(ctx *gin.Context) {
+		var user bindTestUser
+		if err := ctx.ShouldBindJSON(&user); err != nil {
+			if !AbortIfBodyTooLarge(ctx, err) {
+				ctx.AbortWithStatus(http.StatusBadRequest)
+			}
+			return
+		}
+		ctx.Status(http.StatusCreated)
+	})
+	engine.POST("/raw", func(ctx *gin.Context) {
+		if _, err := ctx.GetRawData(); err != nil {
+			AbortIfBodyTooLarge(ctx, err)
+			return
+		}
+		ctx.Status(http.StatusCreated)
+	})
+
+	body := `{"name":"` + strings.Repeat("a", 100) + `"}`
+	for _, path := range []string{"/bind", "/raw"} {
+		req := httptest.NewRequest(http.MethodPost, path, ioutil.NopCloser(strings.NewReader(body)))
+		req.ContentLength = -1
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Test Failed!, %s expected: %v, got: %v", path, http.StatusRequestEntityTooLarge, w.Code)
+		}
+	}
+}
+
+func TestBindStrictJSONConcurrent(t *testing.T) {
+	engine := newBindEngine(1024)
+	engine.POST("/bind", func(ctx *gin.Context) {
+		var user bindTestUser
+		if err := ctx.ShouldBindJSON(&user); err != nil {
+			ctx.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.Status(http.StatusCreated)
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			w := postBody(engine, `{"age":10}`, -1)
+			if !strings.Contains(w.Body.String(), "Field 'name' failed") {
+				t.Errorf("Test Failed!, expected the json field names, got: %v", w.Body.String())
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, "/bind", strings.NewReader(`{"age":10}`))
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+			// the validator of gin is not modified by BindStrictJSON
+			if !strings.Contains(w.Body.String(), "'Name'") {
+				t.Errorf("Test Failed!, expected the struct field names, got: %v", w.Body.String())
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,184 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Request body util constants
+const (
+	formContentType        = "application/x-www-form-urlencoded"
+	octetStreamContentType = "application/octet-stream"
+	contentDispositionKey  = "Content-Disposition"
+
+	multipartFileErrMsg  = "Multipart file for field '%s' must have either a Path or a Reader"
+	multipartWriteErrMsg = "Unable to write multipart body : %v"
+)
+
+// MultipartFileError represents an error when a multipart file is not valid
+type MultipartFileError string
+
+// Error returns the formatted MultipartFileError
+func (mf MultipartFileError) Error() string {
+	return fmt.Sprintf(multipartFileErrMsg, string(mf))
+}
+
+// MultipartWriteError represents an error when the multipart body cannot be written
+type MultipartWriteError struct {
+	Err error
+}
+
+// Error returns the formatted MultipartWriteError
+func (mw MultipartWriteError) Error() string {
+	return fmt.Sprintf(multipartWriteErrMsg, mw.Err)
+}
+
+// MultipartFile represents a file which is uploaded as a part of a multipart body
+// The file is streamed from Path on disk or from the Reader, the file is never loaded into memory
+// FileName defaults to the base name of the Path and ContentType defaults to application/octet-stream
+type MultipartFile struct {
+	FieldName   string
+	FileName    string
+	Path        string
+	Reader      io.Reader
+	ContentType string
+}
+
+// MultipartBody represents a multipart/form-data request body
+type MultipartBody struct {
+	Fields map[string]string
+	Files  []MultipartFile
+}
+
+// Replayable checks if the multipart body can be written more than once
+// Bodies with files streamed from a Reader can only be written once
+func (mb *MultipartBody) Replayable() bool {
+	for _, f := range mb.Files {
+		if f.Path == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks if all the files of the multipart body can be uploaded
+// The method returns an error if a file has no source or if a file on disk does not exist
+func (mb *MultipartBody) Validate() error {
+	for _, f := range mb.Files {
+		if f.Path == "" && f.Reader == nil {
+			return MultipartFileError(f.FieldName)
+		}
+		if f.Path != "" && !FileExists(f.Path) {
+			return FileNotFoundError(f.Path)
+		}
+	}
+	return nil
+}
+
+// multipartReader streams a multipart body which is written by a separate go routine
+// The go routine is started by the first Read so that a body which is never read does not
+// leave a go routine or open files behind
+type multipartReader struct {
+	body  *MultipartBody
+	mw    *multipart.Writer
+	pr    *io.PipeReader
+	pw    *io.PipeWriter
+	start sync.Once
+}
+
+// Read starts writing the body on the first call and reads the written body
+func (mr *multipartReader) Read(p []byte) (int, error) {
+	mr.start.Do(func() {
+		go func() {
+			mr.pw.CloseWithError(mr.body.write(mr.mw))
+		}()
+	})
+	return mr.pr.Read(p)
+}
+
+// Close closes the reader, the go routine writing the body stops with an error
+func (mr *multipartReader) Close() error {
+	return mr.pr.Close()
+}
+
+// Open returns a reader streaming the multipart body and the content type of the body
+// The body is written by a separate go routine while it is being read, the files are only
+// opened once the reader is read, any error while writing the body is returned by the reader
+func (mb *MultipartBody) Open(boundary string) (io.ReadCloser, string, error) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	if boundary != "" {
+		if err := mw.SetBoundary(boundary); err != nil {
+			return nil, "", MultipartWriteError{Err: err}
+		}
+	}
+
+	return &multipartReader{body: mb, mw: mw, pr: pr, pw: pw}, mw.FormDataContentType(), nil
+}
+
+// write writes all the fields and files into the multipart writer
+func (mb *MultipartBody) write(mw *multipart.Writer) error {
+	for k, v := range mb.Fields {
+		if err := mw.WriteField(k, v); err != nil {
+			return MultipartWriteError{Err: err}
+		}
+	}
+
+	for _, f := range mb.Files {
+		if err := f.write(mw); err != nil {
+			return err
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		return MultipartWriteError{Err: err}
+	}
+	return nil
+}
+
+// write copies the file into a new part of the multipart writer
+func (mf *MultipartFile) write(mw *multipart.Writer) error {
+	fileName := mf.FileName
+	if fileName == "" && mf.Path != "" {
+		fileName = filepath.Base(mf.Path)
+	}
+	contentType := mf.ContentType
+	if contentType == "" {
+		contentType = octetStreamContentType
+	}
+
+	h := make(textproto.MIMEHeader)
+	h.Set(contentDispositionKey, fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		escapeQuotes(mf.FieldName), escapeQuotes(fileName)))
+	h.Set(contentTypeKey, contentType)
+
+	part, err := mw.CreatePart(h)
+	if err != nil {
+		return MultipartWriteError{Err: err}
+	}
+
+	src := mf.Reader
+	if mf.Path != "" {
+		f, err := OpenFile(mf.Path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		src = f
+	}
+
+	if _, err := io.Copy(part, src); err != nil {
+		return MultipartWriteError{Err: err}
+	}
+	return nil
+}
+
+// escapeQuotes escapes the quotes and backslashes in a quoted header value
+func escapeQuotes(s string) string {
+	return strings.NewReplacer("\\", "\\\\", `"`, "\\\"").Replace(s)
+}
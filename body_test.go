@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewRequestForm(t *testing.T) {
+	r := Request{Url: "http://localhost", Method: http.MethodPost, Body: RequestBody{Form: url.Values{"name": {"a b"}}}}
+	if err := r.NewRequest(); err != nil {
+		t.Fatal(err)
+	}
+
+	if ct := r.Request.Header.Get(contentTypeKey); ct != formContentType {
+		t.Errorf("Test Failed!, expected: %v, got: %v", formContentType, ct)
+	}
+
+	body, _ := ioutil.ReadAll(r.Request.Body)
+	if string(body) != "name=a+b" {
+		t.Errorf("Test Failed!, expected: %v, got: %v", "name=a+b", string(body))
+	}
+}
+
+func TestNewRequestMultipart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "multipart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "upload.txt")
+	if err := WriteFile(file, []byte("file content")); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f, h, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer f.Close()
+		data, _ := ioutil.ReadAll(f)
+		_, _ = w.Write([]byte(r.FormValue("name") + ":" + h.Filename + ":" + string(data)))
+	}))
+	defer server.Close()
+
+	r := Request{
+		Url:    server.URL,
+		Method: http.MethodPost,
+		Body: RequestBody{Multipart: &MultipartBody{
+			Fields: map[string]string{"name": "test"},
+			Files:  []MultipartFile{{FieldName: "file", Path: file}},
+		}},
+	}
+	if err := r.NewRequest(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.HttpRequest(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "test:upload.txt:file content"
+	if string(r.Result.Body) != expected {
+		t.Errorf("Test Failed!, expected: %v, got: %v", expected, string(r.Result.Body))
+	}
+}
+
+func TestNewRequestMultipartMissingFile(t *testing.T) {
+	r := Request{
+		Url:    "http://localhost",
+		Method: http.MethodPost,
+		Body:   RequestBody{Multipart: &MultipartBody{Files: []MultipartFile{{FieldName: "file", Path: "does-not-exist"}}}},
+	}
+
+	if _, ok := r.NewRequest().(FileNotFoundError); !ok {
+		t.Errorf("Test Failed!, expected: %v", "FileNotFoundError")
+	}
+}
+
+// countingReader counts the calls to Read
+type countingReader struct {
+	reads int32
+}
+
+// Read returns EOF and counts the call
+func (cr *countingReader) Read(p []byte) (int, error) {
+	atomic.AddInt32(&cr.reads, 1)
+	return 0, io.EOF
+}
+
+func TestMultipartBodyOpenLazily(t *testing.T) {
+	src := &countingReader{}
+	mb := MultipartBody{Files: []MultipartFile{{FieldName: "file", Reader: src}}}
+
+	body, _, err := mb.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if n := atomic.LoadInt32(&src.reads); n != 0 {
+		t.Errorf("Test Failed!, expected the file not to be read before the body is read, got %v reads", n)
+	}
+	_ = body.Close()
+
+	body, _, err = mb.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	if _, err := ioutil.ReadAll(body); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&src.reads); n == 0 {
+		t.Error("Test Failed!, expected the file to be read")
+	}
+}
@@ -0,0 +1,525 @@
+package utils
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTP cache util constants
+const (
+	cacheControlKey      = "Cache-Control"
+	etagKey              = "ETag"
+	lastModifiedKey      = "Last-Modified"
+	expiresKey           = "Expires"
+	varyKey              = "Vary"
+	cookieKey            = "Cookie"
+	setCookieKey         = "Set-Cookie"
+	ifNoneMatchKey       = "If-None-Match"
+	ifModifiedSinceKey   = "If-Modified-Since"
+	cacheStatusKey       = "X-Cache"
+	cacheHit             = "HIT"
+	cacheRevalidated     = "REVALIDATED"
+	defaultMaxEntrySize  = 10 << 20
+	cacheFileExtension   = ".json"
+	cacheServedMsg       = "Serving %s %s from the http cache (%s)"
+	cacheStoredMsg       = "Stored %s %s in the http cache, fresh until %v"
+	cacheDirCreateErrMsg = "Unable to create cache directory '%s' : %v"
+)
+
+// CacheDirCreateError represents an error when the cache directory cannot be created
+type CacheDirCreateError struct {
+	Dir string
+	Err error
+}
+
+// Error returns the formatted CacheDirCreateError
+func (cdc CacheDirCreateError) Error() string {
+	return fmt.Sprintf(cacheDirCreateErrMsg, cdc.Dir, cdc.Err)
+}
+
+// credentialsContextKey is the key of the identity of the request credentials in a context.Context
+type credentialsContextKey struct{}
+
+var (
+	// credentialsHashKey is the random key of the credential hashes, it is generated once per process so that
+	// the hashes cannot be used to guess the credentials
+	credentialsHashKey = newCredentialsHashKey()
+)
+
+// newCredentialsHashKey returns a new random key for the credential hashes
+func newCredentialsHashKey() []byte {
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	return key
+}
+
+// credentialsHash returns the hex encoded HMAC of the values joined by colons
+func credentialsHash(values ...string) string {
+	mac := hmac.New(sha256.New, credentialsHashKey)
+	mac.Write([]byte(strings.Join(values, ":")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CachedResponse represents a response stored in the http cache
+// Vary holds the values of the request headers named by the Vary header of the response
+type CachedResponse struct {
+	StatusCode int                 `json:"status_code"`
+	Header     map[string][]string `json:"header"`
+	Body       []byte              `json:"body"`
+	Expires    time.Time           `json:"expires"`
+	Vary       map[string][]string `json:"vary,omitempty"`
+}
+
+// size returns the approximate size of the cached response in bytes
+func (cr *CachedResponse) size() int64 {
+	size := int64(len(cr.Body))
+	for k, values := range cr.Header {
+		for _, v := range values {
+			size += int64(len(k) + len(v))
+		}
+	}
+	return size
+}
+
+// matches checks if the request has the same values as the cached request for the headers named by the Vary header
+func (cr *CachedResponse) matches(req *http.Request) bool {
+	for name, values := range cr.Vary {
+		if strings.Join(req.Header[name], ",") != strings.Join(values, ",") {
+			return false
+		}
+	}
+	return true
+}
+
+// varyValues returns the values of the request headers named by the Vary header of the response
+func varyValues(req *http.Request, header http.Header) map[string][]string {
+	var vary map[string][]string
+	for _, value := range header[varyKey] {
+		for _, name := range strings.Split(value, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			if vary == nil {
+				vary = make(map[string][]string)
+			}
+			vary[name] = append([]string{}, req.Header[name]...)
+		}
+	}
+	return vary
+}
+
+// cacheableHeader returns a copy of the header without the headers which must not be replayed from the cache
+func cacheableHeader(header map[string][]string) map[string][]string {
+	out := make(map[string][]string, len(header))
+	for k, v := range header {
+		if http.CanonicalHeaderKey(k) == setCookieKey {
+			continue
+		}
+		out[k] = append([]string{}, v...)
+	}
+	return out
+}
+
+// fresh checks if the cached response can be served without revalidating it
+func (cr *CachedResponse) fresh() bool {
+	return time.Now().Before(cr.Expires)
+}
+
+// response returns a new http response for the cached response
+func (cr *CachedResponse) response(req *http.Request, status string) *http.Response {
+	header := http.Header{}
+	for k, v := range cr.Header {
+		header[k] = append([]string{}, v...)
+	}
+	header.Set(cacheStatusKey, status)
+	return &http.Response{
+		Status:        StatusString(cr.StatusCode),
+		StatusCode:    cr.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(cr.Body)),
+		ContentLength: int64(len(cr.Body)),
+		Request:       req,
+	}
+}
+
+// CacheStore stores the responses of the http cache
+type CacheStore interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, cr *CachedResponse)
+	Delete(key string)
+}
+
+// memoryCacheEntry represents an entry of the MemoryCacheStore
+type memoryCacheEntry struct {
+	key string
+	cr  *CachedResponse
+}
+
+// MemoryCacheStore is an in memory CacheStore which evicts the least recently used responses
+// once the total size of the stored responses exceeds MaxSize bytes
+type MemoryCacheStore struct {
+	MaxSize int64
+	size    int64
+	entries map[string]*list.Element
+	lru     *list.List
+	mu      sync.Mutex
+}
+
+// NewMemoryCacheStore returns a new MemoryCacheStore
+func NewMemoryCacheStore(maxSize int64) *MemoryCacheStore {
+	return &MemoryCacheStore{MaxSize: maxSize, entries: make(map[string]*list.Element), lru: list.New()}
+}
+
+// Get returns the response stored for the key
+func (mcs *MemoryCacheStore) Get(key string) (*CachedResponse, bool) {
+	mcs.mu.Lock()
+	defer mcs.mu.Unlock()
+	e, ok := mcs.entries[key]
+	if !ok {
+		return nil, false
+	}
+	mcs.lru.MoveToFront(e)
+	return e.Value.(*memoryCacheEntry).cr, true
+}
+
+// Set stores the response for the key
+// Responses larger than MaxSize are not stored
+func (mcs *MemoryCacheStore) Set(key string, cr *CachedResponse) {
+	mcs.mu.Lock()
+	defer mcs.mu.Unlock()
+
+	if mcs.entries == nil {
+		mcs.entries = make(map[string]*list.Element)
+		mcs.lru = list.New()
+	}
+
+	mcs.remove(key)
+	if cr.size() > mcs.MaxSize {
+		return
+	}
+
+	mcs.entries[key] = mcs.lru.PushFront(&memoryCacheEntry{key: key, cr: cr})
+	mcs.size += cr.size()
+
+	for mcs.size > mcs.MaxSize {
+		mcs.remove(mcs.lru.Back().Value.(*memoryCacheEntry).key)
+	}
+}
+
+// Delete removes the response stored for the key
+func (mcs *MemoryCacheStore) Delete(key string) {
+	mcs.mu.Lock()
+	defer mcs.mu.Unlock()
+	mcs.remove(key)
+}
+
+// remove removes the entry, the caller must hold the lock
+func (mcs *MemoryCacheStore) remove(key string) {
+	if e, ok := mcs.entries[key]; ok {
+		mcs.size -= e.Value.(*memoryCacheEntry).cr.size()
+		mcs.lru.Remove(e)
+		delete(mcs.entries, key)
+	}
+}
+
+// DiskCacheStore is a CacheStore which stores the responses as JSON files in Dir
+// The least recently stored responses are removed once the total size of the files exceeds MaxSize bytes
+type DiskCacheStore struct {
+	Dir     string
+	MaxSize int64
+	mu      sync.Mutex
+}
+
+// NewDiskCacheStore creates the cache directory if it does not exist and returns a new DiskCacheStore
+// The method returns an error if the directory cannot be created
+func NewDiskCacheStore(dir string, maxSize int64) (*DiskCacheStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, CacheDirCreateError{Dir: dir, Err: err}
+	}
+	return &DiskCacheStore{Dir: dir, MaxSize: maxSize}, nil
+}
+
+// file returns the file in which the response for the key is stored
+func (dcs *DiskCacheStore) file(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(dcs.Dir, hex.EncodeToString(sum[:])+cacheFileExtension)
+}
+
+// Get returns the response stored for the key
+func (dcs *DiskCacheStore) Get(key string) (*CachedResponse, bool) {
+	dcs.mu.Lock()
+	defer dcs.mu.Unlock()
+	var cr CachedResponse
+	if err := ReadJsonFile(dcs.file(key), &cr); err != nil {
+		return nil, false
+	}
+	return &cr, true
+}
+
+// Set stores the response for the key
+// Responses larger than MaxSize are not stored
+func (dcs *DiskCacheStore) Set(key string, cr *CachedResponse) {
+	dcs.mu.Lock()
+	defer dcs.mu.Unlock()
+
+	file := dcs.file(key)
+	if cr.size() > dcs.MaxSize {
+		_ = os.Remove(file)
+		return
+	}
+	if err := WriteJSONFile(file, cr); err != nil {
+		log := LogFormatter{ErrMsg: err}
+		log.Warn().Println(log.Out)
+		return
+	}
+	dcs.evict()
+}
+
+// Delete removes the response stored for the key
+func (dcs *DiskCacheStore) Delete(key string) {
+	dcs.mu.Lock()
+	defer dcs.mu.Unlock()
+	_ = os.Remove(dcs.file(key))
+}
+
+// evict removes the oldest files until the total size of the files is below MaxSize
+// The caller must hold the lock
+func (dcs *DiskCacheStore) evict() {
+	files, err := filepath.Glob(filepath.Join(dcs.Dir, "*"+cacheFileExtension))
+	if err != nil {
+		return
+	}
+
+	var infos []os.FileInfo
+	var size int64
+	for _, f := range files {
+		if fi, err := os.Stat(f); err == nil {
+			infos = append(infos, fi)
+			size += fi.Size()
+		}
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().Before(infos[j].ModTime())
+	})
+
+	for _, fi := range infos {
+		if size <= dcs.MaxSize {
+			return
+		}
+		if err := os.Remove(filepath.Join(dcs.Dir, fi.Name())); err == nil {
+			size -= fi.Size()
+		}
+	}
+}
+
+// HTTPCache is a private http cache for the GET and HEAD requests of a client
+// Fresh responses are served from the Store, stale responses are revalidated using the ETag and
+// Last-Modified validators and served from the Store when the remote server responds with 304 Not Modified
+// Responses are cached separately for every set of credentials and for the values of the request headers
+// named by the Vary header, the Set-Cookie headers of the responses are never cached
+// Responses larger than MaxEntrySize bytes are not cached, MaxEntrySize defaults to 10 MB
+type HTTPCache struct {
+	Store        CacheStore
+	MaxEntrySize int64
+}
+
+// NewHTTPCache returns a new HTTPCache using the store
+// Register the Middleware of the cache on a Client to enable the cache
+func NewHTTPCache(store CacheStore) *HTTPCache {
+	return &HTTPCache{Store: store, MaxEntrySize: defaultMaxEntrySize}
+}
+
+// parseCacheControl parses the directives of the Cache-Control header
+func parseCacheControl(header http.Header) map[string]string {
+	directives := make(map[string]string)
+	for _, value := range header[http.CanonicalHeaderKey(cacheControlKey)] {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			kv := strings.SplitN(part, "=", 2)
+			v := ""
+			if len(kv) == 2 {
+				v = strings.Trim(strings.TrimSpace(kv[1]), `"`)
+			}
+			directives[strings.ToLower(strings.TrimSpace(kv[0]))] = v
+		}
+	}
+	return directives
+}
+
+// expiry returns the time until which the response is fresh based on the Cache-Control and Expires headers
+func expiry(header http.Header, now time.Time) time.Time {
+	cc := parseCacheControl(header)
+	if _, ok := cc["no-cache"]; ok {
+		return now
+	}
+	if v, ok := cc["max-age"]; ok {
+		if s, err := strconv.Atoi(v); err == nil {
+			return now.Add(time.Duration(s) * time.Second)
+		}
+		return now
+	}
+	if t, err := http.ParseTime(header.Get(expiresKey)); err == nil {
+		return t
+	}
+	return now
+}
+
+// cacheKey returns the key of the request in the cache store
+// Requests made with different credentials or cookies have different keys, the credentials are hashed
+// with a key which is generated per process so entries of requests with credentials do not outlive the process
+func cacheKey(req *http.Request) string {
+	key := req.Method + " " + req.URL.String()
+	id, _ := req.Context().Value(credentialsContextKey{}).(string)
+	auth, cookie := req.Header.Get(authorizationKey), req.Header.Get(cookieKey)
+	if id != "" || auth != "" || cookie != "" {
+		key += " " + credentialsHash(id, auth, cookie)
+	}
+	return key
+}
+
+// Middleware returns a middleware which serves the responses from the cache
+func (hc *HTTPCache) Middleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Method != http.MethodGet && req.Method != http.MethodHead {
+				return next.RoundTrip(req)
+			}
+			if req.Header.Get(ifNoneMatchKey) != "" || req.Header.Get(ifModifiedSinceKey) != "" {
+				// the caller handles the conditional request itself
+				return next.RoundTrip(req)
+			}
+
+			reqCc := parseCacheControl(req.Header)
+			if _, ok := reqCc["no-store"]; ok {
+				return next.RoundTrip(req)
+			}
+
+			key := cacheKey(req)
+			cached, found := hc.Store.Get(key)
+			if found && !cached.matches(req) {
+				found = false
+			}
+
+			if _, noCache := reqCc["no-cache"]; found && !noCache && cached.fresh() {
+				log := LogFormatter{Msg: fmt.Sprintf(cacheServedMsg, req.Method, req.URL, cacheHit)}
+				log.Debug().Println(log.Out)
+				return cached.response(req, cacheHit), nil
+			}
+
+			outReq := req
+			if found {
+				header := http.Header(cached.Header)
+				outReq = req.Clone(req.Context())
+				if etag := header.Get(etagKey); etag != "" {
+					outReq.Header.Set(ifNoneMatchKey, etag)
+				}
+				if lm := header.Get(lastModifiedKey); lm != "" {
+					outReq.Header.Set(ifModifiedSinceKey, lm)
+				}
+			}
+
+			resp, err := next.RoundTrip(outReq)
+			if err != nil {
+				return resp, err
+			}
+
+			now := time.Now()
+
+			if found && resp.StatusCode == http.StatusNotModified {
+				resp.Body.Close()
+				revalidated := &CachedResponse{
+					StatusCode: cached.StatusCode,
+					Header:     cacheableHeader(cached.Header),
+					Body:       cached.Body,
+					Expires:    expiry(resp.Header, now),
+					Vary:       cached.Vary,
+				}
+				for k, v := range cacheableHeader(resp.Header) {
+					revalidated.Header[k] = v
+				}
+				cached = revalidated
+				hc.Store.Set(key, cached)
+
+				log := LogFormatter{Msg: fmt.Sprintf(cacheServedMsg, req.Method, req.URL, cacheRevalidated)}
+				log.Debug().Println(log.Out)
+				return cached.response(req, cacheRevalidated), nil
+			}
+
+			return hc.store(key, req, resp, now), nil
+		})
+	}
+}
+
+// store stores the response in the cache if it is cacheable and returns the response
+// The Set-Cookie headers of the response are not stored
+func (hc *HTTPCache) store(key string, req *http.Request, resp *http.Response, now time.Time) *http.Response {
+	if resp.StatusCode != http.StatusOK {
+		return resp
+	}
+
+	cc := parseCacheControl(resp.Header)
+	if _, ok := cc["no-store"]; ok || resp.Header.Get(varyKey) == "*" {
+		hc.Store.Delete(key)
+		return resp
+	}
+
+	expires := expiry(resp.Header, now)
+	if !expires.After(now) && resp.Header.Get(etagKey) == "" && resp.Header.Get(lastModifiedKey) == "" {
+		return resp
+	}
+
+	maxEntrySize := hc.MaxEntrySize
+	if maxEntrySize == 0 {
+		maxEntrySize = defaultMaxEntrySize
+	}
+	if resp.ContentLength > maxEntrySize {
+		return resp
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxEntrySize+1))
+	if err != nil || int64(len(body)) > maxEntrySize {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp
+	}
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	hc.Store.Set(key, &CachedResponse{
+		StatusCode: resp.StatusCode,
+		Header:     cacheableHeader(resp.Header),
+		Body:       body,
+		Expires:    expires,
+		Vary:       varyValues(req, resp.Header),
+	})
+
+	log := LogFormatter{Msg: fmt.Sprintf(cacheStoredMsg, req.Method, req.URL, expires)}
+	log.Debug().Println(log.Out)
+
+	return resp
+}
@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestHTTPCache(t *testing.T) {
+	var requests, notModified int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path == "/fresh" {
+			w.Header().Set(cacheControlKey, "max-age=60")
+			_, _ = w.Write([]byte("fresh"))
+			return
+		}
+		w.Header().Set(etagKey, `"v1"`)
+		w.Header().Set(cacheControlKey, "no-cache")
+		if r.Header.Get(ifNoneMatchKey) == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	diskStore, err := NewDiskCacheStore(dir, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, store := range []CacheStore{NewMemoryCacheStore(1 << 20), diskStore} {
+		requests, notModified = 0, 0
+
+		client := &Client{}
+		client.Use(NewHTTPCache(store).Middleware())
+
+		for i := 0; i < 3; i++ {
+			for _, path := range []string{"/fresh", "/etag"} {
+				r := Request{Url: server.URL + path, Method: http.MethodGet, Client: client}
+				if err := r.NewRequest(); err != nil {
+					t.Fatal(err)
+				}
+				if err := r.HttpRequest(); err != nil {
+					t.Fatal(err)
+				}
+				if r.Result.StatusCode != http.StatusOK || len(r.Result.Body) == 0 {
+					t.Errorf("Test Failed!, expected: %v, got: %v %v", http.StatusOK, r.Result.StatusCode, string(r.Result.Body))
+				}
+			}
+		}
+
+		if requests != 4 || notModified != 2 {
+			t.Errorf("Test Failed!, expected: %v requests and %v not modified, got: %v and %v", 4, 2, requests, notModified)
+		}
+	}
+}
+
+func TestMemoryCacheStoreEviction(t *testing.T) {
+	store := NewMemoryCacheStore(10)
+	store.Set("a", &CachedResponse{Body: []byte("123456")})
+	store.Set("b", &CachedResponse{Body: []byte("123456")})
+
+	if _, ok := store.Get("a"); ok {
+		t.Errorf("Test Failed!, expected: %v, got: %v", false, ok)
+	}
+	if _, ok := store.Get("b"); !ok {
+		t.Errorf("Test Failed!, expected: %v, got: %v", true, ok)
+	}
+}
+
+func TestHTTPCacheIsolation(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set(cacheControlKey, "max-age=60")
+		w.Header().Set(varyKey, "Accept-Language")
+		w.Header().Set(setCookieKey, "session=abc")
+		user, _, _ := r.BasicAuth()
+		_, _ = w.Write([]byte(user + " " + r.Header.Get("Accept-Language")))
+	}))
+	defer server.Close()
+
+	client := &Client{}
+	client.Use(NewHTTPCache(NewMemoryCacheStore(1 << 20)).Middleware())
+
+	get := func(user, lang string) Result {
+		r := Request{Url: server.URL, Method: http.MethodGet, Client: client, Headers: map[string]string{"Accept-Language": lang}}
+		if user != "" {
+			r.Auth = Auth{Username: user, Password: "pass"}
+		}
+		if err := r.NewRequest(); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.HttpRequest(); err != nil {
+			t.Fatal(err)
+		}
+		return r.Result
+	}
+
+	tests := []struct {
+		user     string
+		lang     string
+		expected string
+		cached   bool
+	}{
+		{"alice", "en", "alice en", false},
+		{"bob", "en", "bob en", false},
+		{"", "en", " en", false},
+		{"alice", "en", "alice en", true},
+		{"alice", "nl", "alice nl", false},
+	}
+
+	for _, test := range tests {
+		result := get(test.user, test.lang)
+		if string(result.Body) != test.expected {
+			t.Errorf("Test Failed!, expected: %v, got: %v", test.expected, string(result.Body))
+		}
+		if cached := result.Header.Get(cacheStatusKey) == cacheHit; cached != test.cached {
+			t.Errorf("Test Failed!, %v expected cached: %v, got: %v", test.expected, test.cached, cached)
+		}
+		if test.cached && result.Header.Get(setCookieKey) != "" {
+			t.Errorf("Test Failed!, expected no Set-Cookie header from the cache, got: %v", result.Header.Get(setCookieKey))
+		}
+	}
+
+	if requests != 4 {
+		t.Errorf("Test Failed!, expected: %v requests, got: %v", 4, requests)
+	}
+}
+
+func TestCacheKeyCredentials(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	req.SetBasicAuth("user", "pass")
+	key := cacheKey(req)
+
+	// the credentials are hashed with the random key of the process
+	plain := hashHex(sha256.New, "", req.Header.Get(authorizationKey), "")
+	if strings.Contains(key, plain) || strings.Contains(key, "pass") {
+		t.Errorf("Test Failed!, expected a keyed hash of the credentials, got: %v", key)
+	}
+	if key != cacheKey(req) {
+		t.Errorf("Test Failed!, expected: %v, got: %v", key, cacheKey(req))
+	}
+}
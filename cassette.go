@@ -0,0 +1,305 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Cassette util constants
+const (
+	cassetteNoMatchErrMsg  = "No recorded interaction in cassette '%s' matches the request %s %s"
+	invalidCassetteModeMsg = "Invalid cassette mode '%d'"
+	cassetteRecordedMsg    = "Recorded interaction %s %s into cassette '%s'"
+	cassetteReplayedMsg    = "Replayed interaction %s %s from cassette '%s'"
+)
+
+// CassetteMode represents the mode in which a cassette is used
+type CassetteMode int
+
+const (
+	// CassetteAuto replays the interactions if the cassette file exists, otherwise the interactions are recorded
+	CassetteAuto CassetteMode = iota
+	// CassetteRecord makes the requests and records the interactions
+	CassetteRecord
+	// CassetteReplay replays the recorded interactions without making any request
+	CassetteReplay
+)
+
+// CassetteNoMatchError represents an error when a request does not match any of the recorded interactions
+type CassetteNoMatchError struct {
+	Cassette string
+	Method   string
+	Url      string
+}
+
+// Error returns the formatted CassetteNoMatchError
+func (cnm CassetteNoMatchError) Error() string {
+	return fmt.Sprintf(cassetteNoMatchErrMsg, cnm.Cassette, cnm.Method, cnm.Url)
+}
+
+// InvalidCassetteModeError represents an error when the cassette mode is not valid
+type InvalidCassetteModeError CassetteMode
+
+// Error returns the formatted InvalidCassetteModeError
+func (icm InvalidCassetteModeError) Error() string {
+	return fmt.Sprintf(invalidCassetteModeMsg, int(icm))
+}
+
+// CassetteRequest represents a recorded request
+type CassetteRequest struct {
+	Method  string              `json:"method" yaml:"method"`
+	Url     string              `json:"url" yaml:"url"`
+	Headers map[string][]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Body    string              `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+// CassetteResponse represents a recorded response
+type CassetteResponse struct {
+	StatusCode int                 `json:"status_code" yaml:"status_code"`
+	Headers    map[string][]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Body       string              `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+// Interaction represents a recorded request and its response
+type Interaction struct {
+	Request  CassetteRequest  `json:"request" yaml:"request"`
+	Response CassetteResponse `json:"response" yaml:"response"`
+}
+
+// CassetteMatcher checks if a request matches a recorded request
+type CassetteMatcher func(req *http.Request, body []byte, recorded CassetteRequest) bool
+
+// MatchMethod matches requests with the same method
+func MatchMethod(req *http.Request, body []byte, recorded CassetteRequest) bool {
+	return strings.EqualFold(req.Method, recorded.Method)
+}
+
+// MatchUrl matches requests with the same url
+func MatchUrl(req *http.Request, body []byte, recorded CassetteRequest) bool {
+	return req.URL.String() == recorded.Url
+}
+
+// MatchBody matches requests with the same body
+func MatchBody(req *http.Request, body []byte, recorded CassetteRequest) bool {
+	return string(body) == recorded.Body
+}
+
+// MatchAll returns a matcher which matches requests matched by all the matchers
+func MatchAll(matchers ...CassetteMatcher) CassetteMatcher {
+	return func(req *http.Request, body []byte, recorded CassetteRequest) bool {
+		for _, m := range matchers {
+			if !m(req, body, recorded) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// DefaultCassetteMatcher matches requests on the method and url
+var DefaultCassetteMatcher = MatchAll(MatchMethod, MatchUrl)
+
+// Cassette records http interactions into a fixture file and replays them in tests
+// The fixture file is written in JSON if the Path has a .json extension, otherwise in YAML
+// The RedactionPolicy is applied to the interactions before they are written, bodies are never truncated
+// Matcher defaults to the DefaultCassetteMatcher
+type Cassette struct {
+	Path            string
+	Mode            CassetteMode
+	Matcher         CassetteMatcher
+	RedactionPolicy RedactionPolicy
+	Interactions    []Interaction
+	replayed        []bool
+	mu              sync.Mutex
+}
+
+// cassetteFile represents the content of a cassette fixture file
+type cassetteFile struct {
+	Interactions []Interaction `json:"interactions" yaml:"interactions"`
+}
+
+// NewCassette returns a new Cassette which redacts the interactions using the DefaultRedactionPolicy
+// The recorded interactions are loaded from the file if the cassette is replayed
+// The method returns an error if the mode is not valid or if the file cannot be read
+func NewCassette(path string, mode CassetteMode) (*Cassette, error) {
+	if mode == CassetteAuto {
+		mode = CassetteRecord
+		if FileExists(path) {
+			mode = CassetteReplay
+		}
+	}
+	if mode != CassetteRecord && mode != CassetteReplay {
+		return nil, InvalidCassetteModeError(mode)
+	}
+
+	c := &Cassette{Path: path, Mode: mode, RedactionPolicy: DefaultRedactionPolicy}
+
+	if mode == CassetteReplay {
+		var cf cassetteFile
+		var err error
+		if c.isJSON() {
+			err = ReadJsonFile(path, &cf)
+		} else {
+			err = ReadYamlFile(path, &cf)
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.Interactions = cf.Interactions
+	}
+
+	return c, nil
+}
+
+// isJSON checks if the cassette is stored as a JSON file
+func (c *Cassette) isJSON() bool {
+	return strings.EqualFold(filepath.Ext(c.Path), ".json")
+}
+
+// Save writes the recorded interactions into the cassette file
+// The method returns an error if the file cannot be written
+func (c *Cassette) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cf := cassetteFile{Interactions: c.Interactions}
+	if c.isJSON() {
+		return WriteJSONFile(c.Path, cf)
+	}
+	return WriteYamlFile(c.Path, cf)
+}
+
+// Middleware returns a middleware which records or replays the interactions
+// Register the middleware on the Client used by the code under test
+func (c *Cassette) Middleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			body, err := readRequestBody(req)
+			if err != nil {
+				return nil, err
+			}
+			if c.Mode == CassetteReplay {
+				return c.replay(req, body)
+			}
+			return c.record(next, req, body)
+		})
+	}
+}
+
+// readRequestBody reads the request body and replaces it with a new reader so the request can still be sent
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, CreateRequestError{Err: err}
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// record makes the request and records the interaction
+func (c *Cassette) record(next http.RoundTripper, req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, ReadResponseError{Err: err}
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	policy := c.RedactionPolicy
+	policy.MaxBodySize = 0
+
+	i := Interaction{
+		Request: CassetteRequest{
+			Method:  req.Method,
+			Url:     req.URL.String(),
+			Headers: policy.RedactHeaders(req.Header),
+			Body:    policy.RedactBody(body, req.Header.Get(contentTypeKey)),
+		},
+		Response: CassetteResponse{
+			StatusCode: resp.StatusCode,
+			Headers:    policy.RedactHeaders(resp.Header),
+			Body:       policy.RedactBody(respBody, resp.Header.Get(contentTypeKey)),
+		},
+	}
+
+	c.mu.Lock()
+	c.Interactions = append(c.Interactions, i)
+	c.mu.Unlock()
+
+	log := LogFormatter{Msg: fmt.Sprintf(cassetteRecordedMsg, req.Method, req.URL, c.Path)}
+	log.Debug().Println(log.Out)
+
+	return resp, nil
+}
+
+// replay returns the response of the first matching interaction which was not replayed yet
+// If all the matching interactions were replayed the last matching interaction is replayed again
+// The request body is redacted before matching so that it can be compared with the recorded body
+func (c *Cassette) replay(req *http.Request, body []byte) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	policy := c.RedactionPolicy
+	policy.MaxBodySize = 0
+	if body != nil {
+		body = []byte(policy.RedactBody(body, req.Header.Get(contentTypeKey)))
+	}
+
+	matcher := c.Matcher
+	if matcher == nil {
+		matcher = DefaultCassetteMatcher
+	}
+	if len(c.replayed) != len(c.Interactions) {
+		c.replayed = make([]bool, len(c.Interactions))
+	}
+
+	match := -1
+	for i, interaction := range c.Interactions {
+		if !matcher(req, body, interaction.Request) {
+			continue
+		}
+		match = i
+		if !c.replayed[i] {
+			break
+		}
+	}
+	if match == -1 {
+		return nil, CassetteNoMatchError{Cassette: c.Path, Method: req.Method, Url: req.URL.String()}
+	}
+	c.replayed[match] = true
+
+	recorded := c.Interactions[match].Response
+	header := http.Header{}
+	for k, v := range recorded.Headers {
+		header[k] = append([]string{}, v...)
+	}
+
+	log := LogFormatter{Msg: fmt.Sprintf(cassetteReplayedMsg, req.Method, req.URL, c.Path)}
+	log.Debug().Println(log.Out)
+
+	return &http.Response{
+		Status:        StatusString(recorded.StatusCode),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCassetteRecordReplay(t *testing.T) {
+	for _, ext := range []string{".yaml", ".json"} {
+		dir, err := ioutil.TempDir("", "cassette")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			w.Header().Set(contentTypeKey, applicationJsonContentType)
+			_, _ = w.Write([]byte(`{"echo":"` + string(body) + `","token":"secret-token"}`))
+		}))
+
+		path := filepath.Join(dir, "cassette"+ext)
+		cassette, err := NewCassette(path, CassetteAuto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cassette.Mode != CassetteRecord {
+			t.Fatalf("Test Failed!, expected: %v, got: %v", CassetteRecord, cassette.Mode)
+		}
+
+		client := &Client{}
+		client.Use(cassette.Middleware())
+
+		r := Request{Url: server.URL + "/echo", Method: http.MethodPost, Body: RequestBody{Text: "hello"}, Auth: Auth{Token: "secret"}, Client: client}
+		if err := r.NewRequest(); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.HttpRequest(); err != nil {
+			t.Fatal(err)
+		}
+		if err := cassette.Save(); err != nil {
+			t.Fatal(err)
+		}
+		server.Close()
+
+		data, err := ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), "secret-token") {
+			t.Errorf("Test Failed!, expected the token to be redacted, got: %v", string(data))
+		}
+
+		cassette, err = NewCassette(path, CassetteAuto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cassette.Matcher = MatchAll(MatchMethod, MatchUrl, MatchBody)
+
+		client = &Client{}
+		client.Use(cassette.Middleware())
+
+		r.Client = client
+		if err := r.NewRequest(); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.HttpRequest(); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := `{"echo":"hello","token":"*****"}`
+		if string(r.Result.Body) != expected || r.Result.StatusCode != http.StatusOK {
+			t.Errorf("Test Failed!, expected: %v, got: %v %v", expected, r.Result.StatusCode, string(r.Result.Body))
+		}
+
+		r.Body.Text = "other"
+		if err := r.NewRequest(); err != nil {
+			t.Fatal(err)
+		}
+		var nm CassetteNoMatchError
+		if err := r.HttpRequest(); !errors.As(err, &nm) {
+			t.Errorf("Test Failed!, expected: %v, got: %v", "CassetteNoMatchError", err)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate limit util constants
+const (
+	rateLimitRemainingKey = "X-RateLimit-Remaining"
+	rateLimitResetKey     = "X-RateLimit-Reset"
+	retryAfterKey         = "Retry-After"
+
+	rateLimitExceededErrMsg  = "Rate limit exceeded for host '%s', retry after %v"
+	invalidRateLimitRuleMsg  = "Invalid rate limit rule for host pattern '%s' : rate and burst must be greater than zero"
+	rateLimitWaitMsg         = "Rate limit reached for host '%s', waiting %v before making the request"
+	rateLimitAdaptedMsg      = "Rate limit for host '%s' was adapted from the response headers, blocked until %v"
+	rateLimitResetEpochLimit = 1000000000
+)
+
+// RateLimitExceededError represents an error when a request is not allowed by the client side rate limiter
+type RateLimitExceededError struct {
+	Host       string
+	RetryAfter time.Duration
+}
+
+// Error returns the formatted RateLimitExceededError
+func (rle RateLimitExceededError) Error() string {
+	return fmt.Sprintf(rateLimitExceededErrMsg, rle.Host, rle.RetryAfter)
+}
+
+// InvalidRateLimitRuleError represents an error when a rate limit rule is not valid
+type InvalidRateLimitRuleError string
+
+// Error returns the formatted InvalidRateLimitRuleError
+func (irl InvalidRateLimitRuleError) Error() string {
+	return fmt.Sprintf(invalidRateLimitRuleMsg, string(irl))
+}
+
+// TokenBucket is a token bucket rate limiter
+// Tokens are added to the bucket at Rate tokens per second up to a maximum of Burst tokens
+type TokenBucket struct {
+	rate         float64
+	burst        float64
+	tokens       float64
+	last         time.Time
+	blockedUntil time.Time
+	mu           sync.Mutex
+}
+
+// NewTokenBucket returns a new full TokenBucket
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// refill adds the tokens accumulated since the last refill to the bucket
+// The caller must hold the lock
+func (tb *TokenBucket) refill(now time.Time) {
+	if now.After(tb.last) {
+		tb.tokens = math.Min(tb.burst, tb.tokens+now.Sub(tb.last).Seconds()*tb.rate)
+		tb.last = now
+	}
+}
+
+// Reserve takes a token from the bucket if one is available
+// The method returns zero if a token was taken or else the duration after which a token will be available
+func (tb *TokenBucket) Reserve() time.Duration {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	now := time.Now()
+	tb.refill(now)
+
+	if now.Before(tb.blockedUntil) {
+		return tb.blockedUntil.Sub(now)
+	}
+	if tb.tokens >= 1 {
+		tb.tokens--
+		return 0
+	}
+	return time.Duration((1 - tb.tokens) / tb.rate * float64(time.Second))
+}
+
+// Wait blocks until a token is available in the bucket or the context is done
+// If failFast is true the method does not block and returns a RateLimitExceededError instead
+func (tb *TokenBucket) Wait(ctx context.Context, host string, failFast bool) error {
+	for {
+		d := tb.Reserve()
+		if d == 0 {
+			return nil
+		}
+		if failFast {
+			return RateLimitExceededError{Host: host, RetryAfter: d}
+		}
+
+		log := LogFormatter{Msg: fmt.Sprintf(rateLimitWaitMsg, host, d)}
+		log.Debug().Println(log.Out)
+
+		timer := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Tokens returns the number of tokens available in the bucket
+func (tb *TokenBucket) Tokens() float64 {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.refill(time.Now())
+	return tb.tokens
+}
+
+// idle returns true if the bucket is full and not blocked, an idle bucket can be discarded
+func (tb *TokenBucket) idle(now time.Time) bool {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.refill(now)
+	return tb.tokens >= tb.burst && !now.Before(tb.blockedUntil)
+}
+
+// Limit caps the number of tokens in the bucket to remaining
+func (tb *TokenBucket) Limit(remaining float64) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.refill(time.Now())
+	if remaining < tb.tokens {
+		tb.tokens = remaining
+	}
+}
+
+// BlockUntil empties the bucket and blocks it until the time t
+func (tb *TokenBucket) BlockUntil(t time.Time) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.tokens = 0
+	tb.last = t
+	if t.After(tb.blockedUntil) {
+		tb.blockedUntil = t
+	}
+}
+
+// RateLimitRule represents the rate limit applied to the hosts matching the Host pattern
+// The Host pattern uses the path.Match syntax, for example "api.github.com" or "*.example.com"
+// All the hosts matching a rule share the same token bucket
+type RateLimitRule struct {
+	Host  string  `yaml:"host" mapstructure:"host"`
+	Rate  float64 `yaml:"rate" mapstructure:"rate"`
+	Burst int     `yaml:"burst" mapstructure:"burst"`
+}
+
+// Validate checks if the values in the RateLimitRule are valid
+func (rr *RateLimitRule) Validate() error {
+	if strings.TrimSpace(rr.Host) == "" {
+		return MissingMandatoryParamError([]string{"host"})
+	}
+	if _, err := path.Match(rr.Host, ""); err != nil {
+		return InvalidRateLimitRuleError(rr.Host)
+	}
+	if rr.Rate <= 0 || rr.Burst <= 0 {
+		return InvalidRateLimitRuleError(rr.Host)
+	}
+	return nil
+}
+
+// RateLimiter is a client side rate limiter which applies a token bucket per host pattern
+// Hosts that do not match any of the rules are not rate limited
+// If FailFast is set requests exceeding the limit fail with a RateLimitExceededError
+// instead of waiting for a token to become available
+type RateLimiter struct {
+	Rules    []RateLimitRule
+	FailFast bool
+	mu       sync.Mutex
+	buckets  map[string]*TokenBucket
+}
+
+// NewRateLimiter validates the rules and returns a new RateLimiter
+// The method returns an error if any of the rules is not valid
+func NewRateLimiter(failFast bool, rules ...RateLimitRule) (*RateLimiter, error) {
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return &RateLimiter{Rules: rules, FailFast: failFast}, nil
+}
+
+// bucket returns the token bucket of the first rule matching the host
+// The method returns nil if the host does not match any rule
+func (rl *RateLimiter) bucket(host string) *TokenBucket {
+	host = strings.ToLower(host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	for _, rule := range rl.Rules {
+		if ok, _ := path.Match(strings.ToLower(rule.Host), host); !ok {
+			continue
+		}
+		rl.mu.Lock()
+		defer rl.mu.Unlock()
+		if rl.buckets == nil {
+			rl.buckets = make(map[string]*TokenBucket)
+		}
+		b, exists := rl.buckets[rule.Host]
+		if !exists {
+			b = NewTokenBucket(rule.Rate, rule.Burst)
+			rl.buckets[rule.Host] = b
+		}
+		return b
+	}
+	return nil
+}
+
+// Wait blocks until the request to the host is allowed by the rate limiter or the context is done
+// The method returns a RateLimitExceededError if FailFast is set and the limit is exceeded
+func (rl *RateLimiter) Wait(ctx context.Context, host string) error {
+	if rl == nil {
+		return nil
+	}
+	b := rl.bucket(host)
+	if b == nil {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return b.Wait(ctx, host, rl.FailFast)
+}
+
+// Update adapts the rate limit of the host based on the X-RateLimit-Remaining,
+// X-RateLimit-Reset and Retry-After headers of the response
+func (rl *RateLimiter) Update(host string, resp *http.Response) {
+	if rl == nil || resp == nil {
+		return
+	}
+	b := rl.bucket(host)
+	if b == nil {
+		return
+	}
+
+	now := time.Now()
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if d, ok := parseRetryAfter(resp.Header.Get(retryAfterKey), now); ok {
+			b.BlockUntil(now.Add(d))
+			log := LogFormatter{Msg: fmt.Sprintf(rateLimitAdaptedMsg, host, now.Add(d))}
+			log.Debug().Println(log.Out)
+			return
+		}
+	}
+
+	remaining, err := strconv.ParseFloat(strings.TrimSpace(resp.Header.Get(rateLimitRemainingKey)), 64)
+	if err != nil {
+		return
+	}
+	if remaining > 0 {
+		b.Limit(remaining)
+		return
+	}
+
+	until, ok := parseRateLimitReset(resp.Header.Get(rateLimitResetKey), now)
+	if !ok {
+		d, retryOk := parseRetryAfter(resp.Header.Get(retryAfterKey), now)
+		if !retryOk {
+			b.Limit(0)
+			return
+		}
+		until = now.Add(d)
+	}
+	b.BlockUntil(until)
+
+	log := LogFormatter{Msg: fmt.Sprintf(rateLimitAdaptedMsg, host, until)}
+	log.Debug().Println(log.Out)
+}
+
+// parseRetryAfter parses the value of a Retry-After header which is either a number of seconds or a HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(value); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if t.Before(now) {
+			return 0, true
+		}
+		return t.Sub(now), true
+	}
+	return 0, false
+}
+
+// parseRateLimitReset parses the value of a X-RateLimit-Reset header
+// which is either a unix timestamp or a number of seconds
+func parseRateLimitReset(value string, now time.Time) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+	s, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || s < 0 {
+		return time.Time{}, false
+	}
+	if s >= rateLimitResetEpochLimit {
+		return time.Unix(s, 0), true
+	}
+	return now.Add(time.Duration(s) * time.Second), true
+}
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterFailFast(t *testing.T) {
+	rl, err := NewRateLimiter(true, RateLimitRule{Host: "*.example.com", Rate: 1, Burst: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := rl.Wait(context.Background(), "api.example.com:443"); err != nil {
+			t.Errorf("Test Failed!, expected: %v, got: %v", nil, err)
+		}
+	}
+
+	err = rl.Wait(context.Background(), "www.example.com")
+	if _, ok := err.(RateLimitExceededError); !ok {
+		t.Errorf("Test Failed!, expected: %v, got: %v", "RateLimitExceededError", err)
+	}
+
+	if err := rl.Wait(context.Background(), "example.org"); err != nil {
+		t.Errorf("Test Failed!, expected: %v, got: %v", nil, err)
+	}
+}
+
+func TestRateLimiterWaitCancel(t *testing.T) {
+	rl, err := NewRateLimiter(false, RateLimitRule{Host: "example.com", Rate: 0.1, Burst: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := rl.Wait(context.Background(), "example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := rl.Wait(ctx, "example.com"); err != context.DeadlineExceeded {
+		t.Errorf("Test Failed!, expected: %v, got: %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestRateLimiterUpdate(t *testing.T) {
+	rl, err := NewRateLimiter(true, RateLimitRule{Host: "example.com", Rate: 100, Burst: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+	resp.Header.Set(retryAfterKey, "60")
+	rl.Update("example.com", resp)
+
+	err = rl.Wait(context.Background(), "example.com")
+	rle, ok := err.(RateLimitExceededError)
+	if !ok || rle.RetryAfter < 59*time.Second {
+		t.Errorf("Test Failed!, expected: %v, got: %v", "retry after 60s", err)
+	}
+}
+
+func TestInvalidRateLimitRule(t *testing.T) {
+	_, err := NewRateLimiter(false, RateLimitRule{Host: "example.com", Rate: 0, Burst: 1})
+	if _, ok := err.(InvalidRateLimitRuleError); !ok {
+		t.Errorf("Test Failed!, expected: %v, got: %v", "InvalidRateLimitRuleError", err)
+	}
+}
+
+func TestHttpRequestRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(rateLimitRemainingKey, "0")
+		w.Header().Set(rateLimitResetKey, "30")
+	}))
+	defer server.Close()
+
+	rl, err := NewRateLimiter(true, RateLimitRule{Host: "127.0.0.1", Rate: 10, Burst: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := Request{Url: server.URL, Method: http.MethodGet, Client: &Client{RateLimiter: rl}}
+	if err := r.NewRequest(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.HttpRequest(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.NewRequest(); err != nil {
+		t.Fatal(err)
+	}
+	err = r.HttpRequest()
+	if _, ok := err.(RateLimitExceededError); !ok {
+		t.Errorf("Test Failed!, expected: %v, got: %v", "RateLimitExceededError", err)
+	}
+}
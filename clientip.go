@@ -0,0 +1,209 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Client IP util constants
+const (
+	forwardedKey     = "Forwarded"
+	forwardedForKey  = "X-Forwarded-For"
+	realIPKey        = "X-Real-Ip"
+	forwardedForAttr = "for"
+
+	invalidTrustedProxyErrMsg    = "Invalid trusted proxy '%s'. The value should be an IP address or a CIDR"
+	invalidForwardedHeaderErrMsg = "Invalid forwarded header '%s'. Valid values are %v"
+)
+
+var (
+	// trustedProxies are the networks of the proxies whose forwarding headers are trusted
+	trustedProxies []*net.IPNet
+	// forwardedHeader is the header in which the trusted proxies forward the client address
+	forwardedHeader  = forwardedForKey
+	trustedProxiesMu sync.RWMutex
+
+	validForwardedHeaders = []string{forwardedForKey, forwardedKey, realIPKey}
+)
+
+// InvalidTrustedProxyError represents an error when a trusted proxy is not an IP address or CIDR
+type InvalidTrustedProxyError string
+
+// Error returns the formatted InvalidTrustedProxyError
+func (itp InvalidTrustedProxyError) Error() string {
+	return fmt.Sprintf(invalidTrustedProxyErrMsg, string(itp))
+}
+
+// InvalidForwardedHeaderError represents an error when the forwarded header is not supported
+type InvalidForwardedHeaderError string
+
+// Error returns the formatted InvalidForwardedHeaderError
+func (ifh InvalidForwardedHeaderError) Error() string {
+	return fmt.Sprintf(invalidForwardedHeaderErrMsg, string(ifh), validForwardedHeaders)
+}
+
+// parseForwardedHeader returns the canonical name of the forwarded header, an empty header defaults to X-Forwarded-For
+func parseForwardedHeader(header string) (string, error) {
+	if strings.TrimSpace(header) == "" {
+		return forwardedForKey, nil
+	}
+	canonical := http.CanonicalHeaderKey(strings.TrimSpace(header))
+	if !EntryExists(validForwardedHeaders, canonical) {
+		return "", InvalidForwardedHeaderError(header)
+	}
+	return canonical, nil
+}
+
+// parseTrustedProxies parses the IP addresses and CIDRs of the trusted proxies
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, InvalidTrustedProxyError(proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, InvalidTrustedProxyError(proxy)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// SetTrustedProxies sets the IP addresses and CIDRs of the proxies whose forwarded header is trusted by GetRequesterIP
+// The method returns an error if a proxy is not an IP address or CIDR
+func SetTrustedProxies(proxies []string) error {
+	networks, err := parseTrustedProxies(proxies)
+	if err != nil {
+		return err
+	}
+	trustedProxiesMu.Lock()
+	defer trustedProxiesMu.Unlock()
+	trustedProxies = networks
+	return nil
+}
+
+// SetForwardedHeader sets the header in which the trusted proxies forward the client address, which is
+// X-Forwarded-For (default), Forwarded or X-Real-Ip
+// Only this header is read by GetRequesterIP, the other headers can be sent by the clients and are ignored
+// The method returns an error if the header is not supported
+func SetForwardedHeader(header string) error {
+	canonical, err := parseForwardedHeader(header)
+	if err != nil {
+		return err
+	}
+	trustedProxiesMu.Lock()
+	defer trustedProxiesMu.Unlock()
+	forwardedHeader = canonical
+	return nil
+}
+
+// isTrustedProxy returns true if the IP belongs to a trusted proxy
+func isTrustedProxy(ip net.IP) bool {
+	trustedProxiesMu.RLock()
+	defer trustedProxiesMu.RUnlock()
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIP parses an IP address which may include a port, IPv6 brackets or an IPv6 zone
+// The method returns nil if the address is not an IP address, for example "unknown" or an obfuscated identifier
+func parseIP(addr string) net.IP {
+	addr = strings.Trim(strings.TrimSpace(addr), `"`)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	addr = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+	if i := strings.IndexByte(addr, '%'); i != -1 {
+		addr = addr[:i]
+	}
+	ip := net.ParseIP(addr)
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	return ip
+}
+
+// forwardedFor returns the addresses of the for parameters of the RFC 7239 Forwarded header
+// in the order in which they were added by the proxies
+func forwardedFor(values []string) []string {
+	var addrs []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+				if len(kv) == 2 && strings.EqualFold(kv[0], forwardedForAttr) {
+					addrs = append(addrs, strings.Trim(kv[1], `"`))
+				}
+			}
+		}
+	}
+	return addrs
+}
+
+// forwardedChain returns the client addresses added by the proxies in the forwarded header
+func forwardedChain(header http.Header) []string {
+	trustedProxiesMu.RLock()
+	name := forwardedHeader
+	trustedProxiesMu.RUnlock()
+
+	switch name {
+	case forwardedKey:
+		return forwardedFor(header[forwardedKey])
+	case realIPKey:
+		if ip := header.Get(realIPKey); ip != "" {
+			return []string{ip}
+		}
+		return nil
+	}
+	var addrs []string
+	for _, value := range header[forwardedForKey] {
+		addrs = append(addrs, strings.Split(value, ",")...)
+	}
+	return addrs
+}
+
+// GetRequesterIP returns the IP of the client which made the request
+// The forwarded header is only used when the request comes from a trusted proxy, see SetTrustedProxies
+// and SetForwardedHeader, and is read from right to left, the first address which is not a trusted proxy is the client IP
+// If the chain contains an address which is not an IP address the last trusted address is returned
+func GetRequesterIP(r *http.Request) string {
+	remote := parseIP(r.RemoteAddr)
+	if remote == nil {
+		return r.RemoteAddr
+	}
+	if !isTrustedProxy(remote) {
+		return remote.String()
+	}
+
+	client := remote
+	chain := forwardedChain(r.Header)
+	for i := len(chain) - 1; i >= 0; i-- {
+		ip := parseIP(chain[i])
+		if ip == nil {
+			break
+		}
+		client = ip
+		if !isTrustedProxy(ip) {
+			break
+		}
+	}
+	return client.String()
+}
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetRequesterIP(t *testing.T) {
+	if err := SetTrustedProxies([]string{"10.0.0.0/8", "2001:db8::/32", "192.168.1.1"}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = SetTrustedProxies(nil)
+		_ = SetForwardedHeader("")
+	}()
+
+	tests := []struct {
+		name            string
+		forwardedHeader string
+		remoteAddr      string
+		headers         map[string][]string
+		expected        string
+	}{
+		{"untrusted remote ignores headers", "", "203.0.113.9:1234", map[string][]string{forwardedForKey: {"1.2.3.4"}}, "203.0.113.9"},
+		{"no headers", "", "10.0.0.1:1234", nil, "10.0.0.1"},
+		{"xff right to left", "", "10.0.0.1:1234", map[string][]string{forwardedForKey: {"6.6.6.6, 1.2.3.4, 10.0.0.2"}}, "1.2.3.4"},
+		{"multiple xff headers", "", "10.0.0.1:1234", map[string][]string{forwardedForKey: {"6.6.6.6", "1.2.3.4"}}, "1.2.3.4"},
+		{"all trusted", "", "10.0.0.1:1234", map[string][]string{forwardedForKey: {"10.0.0.3, 10.0.0.2"}}, "10.0.0.3"},
+		{"invalid entry", "", "10.0.0.1:1234", map[string][]string{forwardedForKey: {"1.2.3.4, garbage, 10.0.0.2"}}, "10.0.0.2"},
+		{"xff proxy ignores spoofed forwarded", "", "10.0.0.1:1234", map[string][]string{
+			forwardedKey:    {"for=6.6.6.6"},
+			forwardedForKey: {"1.2.3.4"},
+		}, "1.2.3.4"},
+		{"xff proxy ignores spoofed real ip", "", "10.0.0.1:1234", map[string][]string{realIPKey: {"6.6.6.6"}}, "10.0.0.1"},
+		{"real ip", realIPKey, "192.168.1.1:1234", map[string][]string{realIPKey: {"1.2.3.4"}}, "1.2.3.4"},
+		{"forwarded", forwardedKey, "10.0.0.1:1234", map[string][]string{
+			forwardedKey:    {`for=6.6.6.6, for="[2001:db8:cafe::17]:4711";proto=https, for=198.51.100.17;by=10.0.0.1`},
+			forwardedForKey: {"7.7.7.7"},
+		}, "198.51.100.17"},
+		{"forwarded ipv6", "forwarded", "[2001:db8::1]:443", map[string][]string{forwardedKey: {`for="[2001:db9::17]:4711"`}}, "2001:db9::17"},
+		{"forwarded unknown", forwardedKey, "10.0.0.1:1234", map[string][]string{forwardedKey: {"for=unknown"}}, "10.0.0.1"},
+		{"ipv4 mapped ipv6 remote", "", "[::ffff:10.0.0.1]:1234", map[string][]string{forwardedForKey: {"1.2.3.4"}}, "1.2.3.4"},
+		{"ipv6 remote with zone", "", "[fe80::1%eth0]:1234", nil, "fe80::1"},
+	}
+
+	for _, test := range tests {
+		if err := SetForwardedHeader(test.forwardedHeader); err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = test.remoteAddr
+		for k, values := range test.headers {
+			for _, v := range values {
+				req.Header.Add(k, v)
+			}
+		}
+		if ip := GetRequesterIP(req); ip != test.expected {
+			t.Errorf("Test Failed!, %s expected: %v, got: %v", test.name, test.expected, ip)
+		}
+	}
+}
+
+func TestSetTrustedProxiesInvalid(t *testing.T) {
+	for _, proxy := range []string{"10.0.0.0/33", "proxy.local"} {
+		if err := SetTrustedProxies([]string{proxy}); err == nil {
+			t.Errorf("Test Failed!, expected an InvalidTrustedProxyError for %v", proxy)
+		}
+	}
+	if err := SetForwardedHeader("X-Client-IP"); err == nil {
+		t.Error("Test Failed!, expected an InvalidForwardedHeaderError")
+	}
+}
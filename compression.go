@@ -0,0 +1,201 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Compression util constants
+const (
+	acceptEncodingKey         = "Accept-Encoding"
+	contentEncodingKey        = "Content-Encoding"
+	contentLengthKey          = "Content-Length"
+	decodedContentEncodingKey = "X-Decoded-Content-Encoding"
+	gzipEncoding              = "gzip"
+	deflateEncoding           = "deflate"
+	brotliEncoding            = "br"
+	identityEncoding          = "identity"
+	supportedEncodings        = "gzip, deflate, br"
+
+	compressBodyErrMsg    = "Unable to compress request body : %v"
+	decompressBodyErrMsg  = "Unable to decode response body with content encoding '%s' : %v"
+	requestCompressedMsg  = "Compressed request body from %d to %d bytes"
+	responseDecompressMsg = "Decoding response body with content encoding '%s'"
+)
+
+// CompressBodyError represents an error when the request body cannot be compressed
+type CompressBodyError struct {
+	Err error
+}
+
+// Error returns the formatted CompressBodyError
+func (cb CompressBodyError) Error() string {
+	return fmt.Sprintf(compressBodyErrMsg, cb.Err)
+}
+
+// DecompressBodyError represents an error when the response body cannot be decoded
+type DecompressBodyError struct {
+	Encoding string
+	Err      error
+}
+
+// Error returns the formatted DecompressBodyError
+func (db DecompressBodyError) Error() string {
+	return fmt.Sprintf(decompressBodyErrMsg, db.Encoding, db.Err)
+}
+
+// decodedBody closes both the decoder and the underlying response body
+type decodedBody struct {
+	io.Reader
+	decoder io.Closer
+	body    io.Closer
+}
+
+// Close closes the decoder and the response body
+func (db *decodedBody) Close() error {
+	if db.decoder != nil {
+		db.decoder.Close()
+	}
+	return db.body.Close()
+}
+
+// newDecoder returns a reader which decodes the body using the content encoding
+// Unknown encodings return the body as it is and an empty body is returned without a decoder
+func newDecoder(encoding string, body io.Reader) (io.Reader, io.Closer, error) {
+	switch encoding {
+	case gzipEncoding, "x-gzip", deflateEncoding, brotliEncoding:
+	default:
+		return body, nil, nil
+	}
+
+	br := bufio.NewReader(body)
+	if _, err := br.Peek(1); err == io.EOF {
+		return br, nil, nil
+	}
+
+	switch encoding {
+	case gzipEncoding, "x-gzip":
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, nil, err
+		}
+		return zr, zr, nil
+	case deflateEncoding:
+		// deflate should be zlib wrapped but some servers send raw deflate data
+		if h, err := br.Peek(2); err == nil && h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0 {
+			zr, err := zlib.NewReader(br)
+			if err != nil {
+				return nil, nil, err
+			}
+			return zr, zr, nil
+		}
+		fr := flate.NewReader(br)
+		return fr, fr, nil
+	}
+	return brotli.NewReader(br), nil, nil
+}
+
+// compressRequest gzips the request body if it is larger than the threshold
+// Only bodies which can be replayed are compressed
+func compressRequest(req *http.Request, threshold int) (*http.Request, error) {
+	if threshold <= 0 || req.GetBody == nil || req.ContentLength < int64(threshold) ||
+		req.Header.Get(contentEncodingKey) != "" {
+		return req, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, CompressBodyError{Err: err}
+	}
+	defer body.Close()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := io.Copy(zw, body); err != nil {
+		return nil, CompressBodyError{Err: err}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, CompressBodyError{Err: err}
+	}
+
+	log := LogFormatter{Msg: fmt.Sprintf(requestCompressedMsg, req.ContentLength, buf.Len())}
+	log.Debug().Println(log.Out)
+
+	compressed := buf.Bytes()
+	out := req.Clone(req.Context())
+	out.Body = ioutil.NopCloser(bytes.NewReader(compressed))
+	out.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(compressed)), nil
+	}
+	out.ContentLength = int64(len(compressed))
+	out.Header.Set(contentEncodingKey, gzipEncoding)
+
+	return out, nil
+}
+
+// CompressionMiddleware returns a middleware which compresses the request bodies larger than threshold
+// bytes with gzip and decodes the gzip, deflate and br encoded response bodies
+// The Accept-Encoding header is set if the request does not set it and is not a Range request, the responses
+// are decoded even if the Accept-Encoding header was set by the caller, a threshold of zero disables the
+// request compression, partial content responses are never decoded
+// The decoded content encoding is registered in the X-Decoded-Content-Encoding header of the response,
+// HttpRequest moves it to the ContentEncoding of the Result
+func CompressionMiddleware(threshold int) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req, err := compressRequest(req, threshold)
+			if err != nil {
+				return nil, err
+			}
+
+			if req.Header.Get(acceptEncodingKey) == "" && req.Header.Get(rangeKey) == "" {
+				req = req.Clone(req.Context())
+				req.Header.Set(acceptEncodingKey, supportedEncodings)
+			}
+
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				return resp, err
+			}
+
+			encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get(contentEncodingKey)))
+			if encoding == "" || encoding == identityEncoding || req.Method == http.MethodHead ||
+				resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified ||
+				resp.StatusCode == http.StatusPartialContent {
+				return resp, nil
+			}
+
+			log := LogFormatter{Msg: fmt.Sprintf(responseDecompressMsg, encoding)}
+			log.Debug().Println(log.Out)
+
+			reader, decoder, err := newDecoder(encoding, resp.Body)
+			if err != nil {
+				resp.Body.Close()
+				return nil, DecompressBodyError{Encoding: encoding, Err: err}
+			}
+			if reader == resp.Body {
+				// unknown encodings are handed back as they are
+				return resp, nil
+			}
+
+			resp.Body = &decodedBody{Reader: reader, decoder: decoder, body: resp.Body}
+			resp.Header.Del(contentEncodingKey)
+			resp.Header.Del(contentLengthKey)
+			resp.Header.Set(decodedContentEncodingKey, encoding)
+			resp.ContentLength = -1
+			resp.Uncompressed = true
+
+			return resp, nil
+		})
+	}
+}
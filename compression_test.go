@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestCompression(t *testing.T) {
+	content := strings.Repeat("compressible content ", 100)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body io.Reader = r.Body
+		if r.Header.Get(contentEncodingKey) == gzipEncoding {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			body = zr
+		}
+		data, _ := ioutil.ReadAll(body)
+
+		var buf bytes.Buffer
+		var zw io.WriteCloser
+		encoding := r.URL.Query().Get("encoding")
+		switch encoding {
+		case gzipEncoding:
+			zw = gzip.NewWriter(&buf)
+		case deflateEncoding:
+			zw = zlib.NewWriter(&buf)
+		case brotliEncoding:
+			zw = brotli.NewWriter(&buf)
+		}
+		_, _ = zw.Write(data)
+		_ = zw.Close()
+
+		w.Header().Set(contentEncodingKey, encoding)
+		w.Header().Set("X-Request-Encoding", r.Header.Get(contentEncodingKey))
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	client := &Client{CompressionThreshold: 1024}
+
+	for _, encoding := range []string{gzipEncoding, deflateEncoding, brotliEncoding} {
+		r := Request{
+			Url:     server.URL,
+			Method:  http.MethodPost,
+			Body:    RequestBody{Text: content},
+			Headers: map[string]string{acceptEncodingKey: encoding},
+			Query:   map[string]string{"encoding": encoding},
+			Client:  client,
+		}
+		if err := r.NewRequest(); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.HttpRequest(); err != nil {
+			t.Fatal(err)
+		}
+
+		if string(r.Result.Body) != content {
+			t.Errorf("Test Failed! %s, expected: %v bytes, got: %v bytes", encoding, len(content), len(r.Result.Body))
+		}
+		if r.Result.ContentEncoding != encoding || r.Result.DecodedSize != int64(len(content)) {
+			t.Errorf("Test Failed!, expected: %v %v, got: %v %v", encoding, len(content), r.Result.ContentEncoding, r.Result.DecodedSize)
+		}
+		if r.Result.Header.Get("X-Request-Encoding") != gzipEncoding {
+			t.Errorf("Test Failed!, expected: %v, got: %v", gzipEncoding, r.Result.Header.Get("X-Request-Encoding"))
+		}
+		if v := r.Result.Header.Get(decodedContentEncodingKey); v != "" {
+			t.Errorf("Test Failed!, expected no %v header, got: %v", decodedContentEncodingKey, v)
+		}
+	}
+}
+
+func TestCompressionRangeAndEmptyBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Accept-Encoding", r.Header.Get(acceptEncodingKey))
+		if r.Header.Get(rangeKey) != "" {
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write([]byte("partial"))
+			return
+		}
+		// an empty gzip encoded body, as sent by some servers for empty responses
+		w.Header().Set(contentEncodingKey, gzipEncoding)
+	}))
+	defer server.Close()
+
+	r := Request{Url: server.URL, Method: http.MethodGet, Headers: map[string]string{rangeKey: "bytes=10-"}}
+	if err := r.NewRequest(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.HttpRequest(); err != nil {
+		t.Fatal(err)
+	}
+	if v := r.Result.Header.Get("X-Accept-Encoding"); v != "" || string(r.Result.Body) != "partial" {
+		t.Errorf("Test Failed!, expected no Accept-Encoding for a range request, got: %v %v", v, string(r.Result.Body))
+	}
+
+	r = Request{Url: server.URL, Method: http.MethodGet}
+	if err := r.NewRequest(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.HttpRequest(); err != nil {
+		t.Fatal(err)
+	}
+	if r.Result.StatusCode != http.StatusOK || len(r.Result.Body) != 0 {
+		t.Errorf("Test Failed!, expected an empty body, got: %v %v", r.Result.StatusCode, string(r.Result.Body))
+	}
+}
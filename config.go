@@ -3,6 +3,7 @@ package utils
 import (
 	"fmt"
 	"strings"
+	"time"
 )
 
 const (
@@ -11,6 +12,8 @@ const (
 	cnfProxyProtocolKey = "server.http.proxy_protocol"
 	cnfProxyHostKey     = "server.http.proxy_host"
 	cnfProxyPortKey     = "server.http.proxy_port"
+	cnfTLSCertFileKey   = "server.tls.cert_file"
+	cnfTLSKeyFileKey    = "server.tls.key_file"
 
 	ConfigLoadedSuccessMsg    = "Configuration was loaded successfully from %s"
 	ConfigChangeDetectedMsg   = "A configuration change was detected in the config file '%s'"
@@ -104,13 +107,56 @@ func (hc *HTTPCnf) Set() {
 	ProxyPort = hc.ProxyPort
 }
 
+// TLSCnf represents the servers TLS configuration
+type TLSCnf struct {
+	Enable   bool   `yaml:"enable" mapstructure:"enable"`
+	CertFile string `yaml:"cert_file" mapstructure:"cert_file"`
+	KeyFile  string `yaml:"key_file" mapstructure:"key_file"`
+}
+
+// Validate checks if the values in the TLSCnf are valid
+func (tc *TLSCnf) Validate() error {
+
+	var missingParams []string
+
+	if tc.Enable {
+		if strings.TrimSpace(tc.CertFile) == "" {
+			missingParams = append(missingParams, cnfTLSCertFileKey)
+		}
+		if strings.TrimSpace(tc.KeyFile) == "" {
+			missingParams = append(missingParams, cnfTLSKeyFileKey)
+		}
+
+		if len(missingParams) != 0 {
+			return MissingMandatoryParamError(missingParams)
+		}
+	}
+
+	return nil
+}
+
 // ServerCnf represents the server configuration
-// It includes the basic host + port config along with the Logger and HTTP configurations
+// It includes the basic host + port config along with the Logger, HTTP, TLS, access log, CORS,
+// rate limit and metrics configurations
+// TrustedProxies are the IP addresses and CIDRs of the proxies whose forwarded header is trusted and
+// ForwardedHeader is the header the proxies set, X-Forwarded-For (default), Forwarded or X-Real-Ip
+// ShutdownTimeout is the time given to the in-flight requests to complete when the server is shut down
+// DrainDelay is the time between marking the service as not ready and closing the listeners when the server
+// is shut down, it should be longer than the interval at which the load balancers check /readyz
 type ServerCnf struct {
-	Host      string `yaml:"host" mapstructure:"host"`
-	Port      string `yaml:"port" mapstructure:"port"`
-	LoggerCnf `yaml:"logging" mapstructure:"logging"`
-	HTTPCnf   `yaml:"http" mapstructure:"http"`
+	Host            string        `yaml:"host" mapstructure:"host"`
+	Port            string        `yaml:"port" mapstructure:"port"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" mapstructure:"shutdown_timeout"`
+	DrainDelay      time.Duration `yaml:"drain_delay" mapstructure:"drain_delay"`
+	TrustedProxies  []string      `yaml:"trusted_proxies" mapstructure:"trusted_proxies"`
+	ForwardedHeader string        `yaml:"forwarded_header" mapstructure:"forwarded_header"`
+	LoggerCnf       `yaml:"logging" mapstructure:"logging"`
+	HTTPCnf         `yaml:"http" mapstructure:"http"`
+	TLSCnf          TLSCnf       `yaml:"tls" mapstructure:"tls"`
+	AccessLogCnf    AccessLogCnf `yaml:"access_log" mapstructure:"access_log"`
+	CORSCnf         CORSCnf      `yaml:"cors" mapstructure:"cors"`
+	RateLimitCnf    RateLimitCnf `yaml:"rate_limit" mapstructure:"rate_limit"`
+	MetricsCnf      MetricsCnf   `yaml:"metrics" mapstructure:"metrics"`
 }
 
 // Validate validates if the sonar configuration provided in the configuration file is valid
@@ -129,6 +175,14 @@ func (sc *ServerCnf) Validate() error {
 		return MissingMandatoryParamError(missingParams)
 	}
 
+	if _, err := parseTrustedProxies(sc.TrustedProxies); err != nil {
+		return err
+	}
+
+	if _, err := parseForwardedHeader(sc.ForwardedHeader); err != nil {
+		return err
+	}
+
 	if err := sc.LoggerCnf.Validate(); err != nil {
 		return err
 	}
@@ -137,5 +191,30 @@ func (sc *ServerCnf) Validate() error {
 		return err
 	}
 
+	if err := sc.TLSCnf.Validate(); err != nil {
+		return err
+	}
+
+	if err := sc.AccessLogCnf.Validate(); err != nil {
+		return err
+	}
+
+	if err := sc.CORSCnf.Validate(); err != nil {
+		return err
+	}
+
+	if err := sc.RateLimitCnf.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
+
+// Set sets the logging and http config, the trusted proxies and the forwarded header
+func (sc *ServerCnf) Set() {
+	sc.LoggerCnf.Set()
+	sc.HTTPCnf.Set()
+	// the trusted proxies and the forwarded header are validated by Validate
+	_ = SetTrustedProxies(sc.TrustedProxies)
+	_ = SetForwardedHeader(sc.ForwardedHeader)
+}
@@ -1,9 +1,13 @@
 package utils
 
 const (
-	PathNotFoundMsg      = "404 Path Not Found"
-	MethodNotAllowedMsg  = "405 Method Not Allowed"
-	InternalServerErrMsg = "500 Internal Server Error : Please contact your system administrator"
-	StartingServerMsg    = "Starting the API server..."
-	StartedServerMsg     = "The API server has started and is listening on %s"
+	PathNotFoundMsg          = "404 Path Not Found"
+	MethodNotAllowedMsg      = "405 Method Not Allowed"
+	InternalServerErrMsg     = "500 Internal Server Error : Please contact your system administrator"
+	TooManyRequestsMsg       = "429 Too Many Requests : Please retry later"
+	RequestEntityTooLargeMsg = "413 Request Entity Too Large"
+	StartingServerMsg        = "Starting the API server..."
+	StartedServerMsg         = "The API server has started and is listening on %s"
+	StoppingServerMsg        = "Shutting down the API server..."
+	StoppedServerMsg         = "The API server has stopped"
 )
@@ -0,0 +1,265 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORS util constants
+const (
+	cnfCORSAllowedOriginsKey = "server.cors.allowed_origins"
+
+	originKey                 = "Origin"
+	allowOriginKey            = "Access-Control-Allow-Origin"
+	allowMethodsKey           = "Access-Control-Allow-Methods"
+	allowHeadersKey           = "Access-Control-Allow-Headers"
+	allowCredentialsKey       = "Access-Control-Allow-Credentials"
+	exposeHeadersKey          = "Access-Control-Expose-Headers"
+	maxAgeKey                 = "Access-Control-Max-Age"
+	requestMethodKey          = "Access-Control-Request-Method"
+	requestHeadersKey         = "Access-Control-Request-Headers"
+	corsWildcard              = "*"
+	corsRegexPrefix           = "^"
+	invalidCORSMethodErrMsg   = "Invalid CORS method '%s'. Valid values are %v"
+	invalidCORSCredentialsMsg = "CORS credentials cannot be allowed for the origin '%s', list the allowed origins instead"
+	corsPreflightRejectedMsg  = "CORS preflight request from origin '%s' was rejected"
+	defaultCORSAllowedHeaders = "Origin, Accept, Content-Type, X-Requested-With"
+)
+
+var (
+	validCORSMethods = []string{
+		http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions,
+	}
+	defaultCORSMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+)
+
+// InvalidCORSMethodError represents an error when a CORS method is not valid
+type InvalidCORSMethodError string
+
+// Error returns the formatted InvalidCORSMethodError
+func (icm InvalidCORSMethodError) Error() string {
+	return fmt.Sprintf(invalidCORSMethodErrMsg, string(icm), validCORSMethods)
+}
+
+// InvalidCORSCredentialsError represents an error when credentials are allowed for any origin
+type InvalidCORSCredentialsError string
+
+// Error returns the formatted InvalidCORSCredentialsError
+func (icc InvalidCORSCredentialsError) Error() string {
+	return fmt.Sprintf(invalidCORSCredentialsMsg, string(icc))
+}
+
+// CORSCnf represents the cross-origin resource sharing configuration of the server
+// AllowedOrigins are exact origins, "*" for any origin, wildcard patterns like "https://*.example.com"
+// or regular expressions which start with "^"
+// AllowedMethods default to GET, HEAD and POST and AllowedHeaders default to Origin, Accept, Content-Type
+// and X-Requested-With, "*" allows any header
+// AllowCredentials cannot be combined with the "*" origin, the credentials are only allowed for the listed origins
+// MaxAge is the time the result of a preflight request can be cached by the browser
+type CORSCnf struct {
+	Enable           bool          `yaml:"enable" mapstructure:"enable"`
+	AllowedOrigins   []string      `yaml:"allowed_origins" mapstructure:"allowed_origins"`
+	AllowedMethods   []string      `yaml:"allowed_methods" mapstructure:"allowed_methods"`
+	AllowedHeaders   []string      `yaml:"allowed_headers" mapstructure:"allowed_headers"`
+	ExposedHeaders   []string      `yaml:"exposed_headers" mapstructure:"exposed_headers"`
+	AllowCredentials bool          `yaml:"allow_credentials" mapstructure:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age" mapstructure:"max_age"`
+}
+
+// Validate checks if the values in the CORSCnf are valid
+func (cc *CORSCnf) Validate() error {
+	if !cc.Enable {
+		return nil
+	}
+
+	if len(cc.AllowedOrigins) == 0 {
+		return MissingMandatoryParamError([]string{cnfCORSAllowedOriginsKey})
+	}
+
+	for _, origin := range cc.AllowedOrigins {
+		if origin == corsWildcard && cc.AllowCredentials {
+			return InvalidCORSCredentialsError(origin)
+		}
+		if strings.HasPrefix(origin, corsRegexPrefix) {
+			if _, err := regexp.Compile(origin); err != nil {
+				return RegexCompileError{Err: err}
+			}
+		}
+	}
+
+	for _, method := range cc.AllowedMethods {
+		if !EntryExists(validCORSMethods, strings.ToUpper(method)) {
+			return InvalidCORSMethodError(method)
+		}
+	}
+
+	return nil
+}
+
+// corsPolicy is the compiled CORSCnf used by the middleware
+type corsPolicy struct {
+	cnf         CORSCnf
+	allowAll    bool
+	origins     map[string]bool
+	patterns    []string
+	regexes     []*regexp.Regexp
+	methods     []string
+	headers     []string
+	allHeaders  bool
+	allowHeader string
+}
+
+// newCORSPolicy compiles the CORS configuration
+func newCORSPolicy(cnf CORSCnf) (*corsPolicy, error) {
+	if err := cnf.Validate(); err != nil {
+		return nil, err
+	}
+
+	p := &corsPolicy{cnf: cnf, origins: make(map[string]bool)}
+	for _, origin := range cnf.AllowedOrigins {
+		switch {
+		case origin == corsWildcard:
+			p.allowAll = true
+		case strings.HasPrefix(origin, corsRegexPrefix):
+			// the regex is already validated
+			p.regexes = append(p.regexes, regexp.MustCompile(origin))
+		case strings.Contains(origin, corsWildcard):
+			p.patterns = append(p.patterns, strings.ToLower(origin))
+		default:
+			p.origins[strings.ToLower(origin)] = true
+		}
+	}
+
+	p.methods = defaultCORSMethods
+	if len(cnf.AllowedMethods) != 0 {
+		p.methods = nil
+		for _, method := range cnf.AllowedMethods {
+			p.methods = append(p.methods, strings.ToUpper(method))
+		}
+	}
+
+	p.allowHeader = defaultCORSAllowedHeaders
+	if len(cnf.AllowedHeaders) != 0 {
+		p.allowHeader = strings.Join(cnf.AllowedHeaders, ", ")
+	}
+	for _, header := range strings.Split(p.allowHeader, ",") {
+		header = strings.TrimSpace(header)
+		if header == corsWildcard {
+			p.allHeaders = true
+		}
+		p.headers = append(p.headers, http.CanonicalHeaderKey(header))
+	}
+
+	return p, nil
+}
+
+// allowedOrigin returns true if the origin is allowed
+func (p *corsPolicy) allowedOrigin(origin string) bool {
+	if p.allowAll {
+		return true
+	}
+	lower := strings.ToLower(origin)
+	if p.origins[lower] {
+		return true
+	}
+	for _, pattern := range p.patterns {
+		if ok, _ := path.Match(pattern, lower); ok {
+			return true
+		}
+	}
+	for _, re := range p.regexes {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedHeaders returns true if all the headers requested by a preflight request are allowed
+func (p *corsPolicy) allowedHeaders(requested string) bool {
+	if p.allHeaders {
+		return true
+	}
+	for _, header := range strings.Split(requested, ",") {
+		header = strings.TrimSpace(header)
+		if header != "" && !EntryExists(p.headers, http.CanonicalHeaderKey(header)) {
+			return false
+		}
+	}
+	return true
+}
+
+// setOrigin sets the allowed origin and credentials headers of the response
+func (p *corsPolicy) setOrigin(ctx *gin.Context, origin string) {
+	if p.allowAll {
+		// Validate rejects the "*" origin together with AllowCredentials
+		ctx.Header(allowOriginKey, corsWildcard)
+	} else {
+		ctx.Header(allowOriginKey, origin)
+	}
+	if p.cnf.AllowCredentials {
+		ctx.Header(allowCredentialsKey, "true")
+	}
+}
+
+// CORS is a gin middleware which handles cross-origin requests based on the CORS configuration
+// Preflight requests are answered with 204 No Content if the origin, method and headers are allowed and
+// rejected with 403 Forbidden otherwise, other requests from origins which are not allowed are served
+// without CORS headers so that the browser blocks the response
+// The method returns an error if the configuration is not valid
+func CORS(cnf CORSCnf) (gin.HandlerFunc, error) {
+	p, err := newCORSPolicy(cnf)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx *gin.Context) {
+		origin := ctx.GetHeader(originKey)
+		if origin == "" {
+			ctx.Next()
+			return
+		}
+		ctx.Writer.Header().Add(varyKey, originKey)
+
+		preflight := ctx.Request.Method == http.MethodOptions && ctx.GetHeader(requestMethodKey) != ""
+		if !preflight {
+			if p.allowedOrigin(origin) {
+				p.setOrigin(ctx, origin)
+				if len(cnf.ExposedHeaders) != 0 {
+					ctx.Header(exposeHeadersKey, strings.Join(cnf.ExposedHeaders, ", "))
+				}
+			}
+			ctx.Next()
+			return
+		}
+
+		requestedHeaders := ctx.GetHeader(requestHeadersKey)
+		if !p.allowedOrigin(origin) || !EntryExists(p.methods, strings.ToUpper(ctx.GetHeader(requestMethodKey))) ||
+			!p.allowedHeaders(requestedHeaders) {
+			log := LogFormatter{Request: ctx.Request, StatusCode: http.StatusForbidden, Msg: fmt.Sprintf(corsPreflightRejectedMsg, origin)}
+			log.Info().Println(log.Out)
+			ctx.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		p.setOrigin(ctx, origin)
+		ctx.Header(allowMethodsKey, strings.Join(p.methods, ", "))
+		if p.allHeaders && requestedHeaders != "" {
+			ctx.Header(allowHeadersKey, requestedHeaders)
+		} else {
+			ctx.Header(allowHeadersKey, p.allowHeader)
+		}
+		if cnf.MaxAge > 0 {
+			ctx.Header(maxAgeKey, strconv.Itoa(int(cnf.MaxAge.Seconds())))
+		}
+		ctx.AbortWithStatus(http.StatusNoContent)
+	}, nil
+}
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCORS(t *testing.T) {
+	cors, err := CORS(CORSCnf{
+		Enable:           true,
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org", `^https://[a-z]+\.test$`},
+		AllowedMethods:   []string{"get", "put"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		ExposedHeaders:   []string{RequestIDHeader},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	engine := gin.New()
+	engine.Use(cors)
+	engine.GET("/items", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "ok")
+	})
+
+	tests := []struct {
+		method, origin, requestMethod, requestHeaders string
+		statusCode                                    int
+		allowOrigin                                   string
+	}{
+		{http.MethodGet, "https://app.example.com", "", "", http.StatusOK, "https://app.example.com"},
+		{http.MethodGet, "https://api.example.org", "", "", http.StatusOK, "https://api.example.org"},
+		{http.MethodGet, "https://abc.test", "", "", http.StatusOK, "https://abc.test"},
+		{http.MethodGet, "https://evil.com", "", "", http.StatusOK, ""},
+		{http.MethodGet, "", "", "", http.StatusOK, ""},
+		{http.MethodOptions, "https://app.example.com", http.MethodPut, "content-type, authorization", http.StatusNoContent, "https://app.example.com"},
+		{http.MethodOptions, "https://app.example.com", http.MethodDelete, "", http.StatusForbidden, ""},
+		{http.MethodOptions, "https://app.example.com", http.MethodGet, "X-Custom", http.StatusForbidden, ""},
+		{http.MethodOptions, "https://evil.com", http.MethodGet, "", http.StatusForbidden, ""},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, "/items", nil)
+		if test.origin != "" {
+			req.Header.Set(originKey, test.origin)
+		}
+		if test.requestMethod != "" {
+			req.Header.Set(requestMethodKey, test.requestMethod)
+		}
+		if test.requestHeaders != "" {
+			req.Header.Set(requestHeadersKey, test.requestHeaders)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+
+		if w.Code != test.statusCode {
+			t.Errorf("Test Failed!, %s %s expected: %v, got: %v", test.method, test.origin, test.statusCode, w.Code)
+		}
+		if got := w.Header().Get(allowOriginKey); got != test.allowOrigin {
+			t.Errorf("Test Failed!, %s %s expected origin: %q, got: %q", test.method, test.origin, test.allowOrigin, got)
+		}
+		if test.allowOrigin == "" {
+			continue
+		}
+		if w.Header().Get(allowCredentialsKey) != "true" {
+			t.Errorf("Test Failed!, expected credentials to be allowed for %s", test.origin)
+		}
+		if test.method == http.MethodOptions {
+			if w.Header().Get(allowMethodsKey) != "GET, PUT" || w.Header().Get(maxAgeKey) != "3600" {
+				t.Errorf("Test Failed!, unexpected preflight headers: %v", w.Header())
+			}
+		} else if w.Header().Get(exposeHeadersKey) != RequestIDHeader {
+			t.Errorf("Test Failed!, expected: %v, got: %v", RequestIDHeader, w.Header().Get(exposeHeadersKey))
+		}
+	}
+}
+
+func TestCORSAllowAll(t *testing.T) {
+	cors, err := CORS(CORSCnf{Enable: true, AllowedOrigins: []string{"*"}, AllowedHeaders: []string{"*"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.ReleaseMode)
+	engine := gin.New()
+	engine.Use(cors)
+
+	req := httptest.NewRequest(http.MethodOptions, "/", nil)
+	req.Header.Set(originKey, "https://any.com")
+	req.Header.Set(requestMethodKey, http.MethodPost)
+	req.Header.Set(requestHeadersKey, "X-Custom")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent || w.Header().Get(allowOriginKey) != "*" || w.Header().Get(allowHeadersKey) != "X-Custom" {
+		t.Errorf("Test Failed!, unexpected preflight response: %v %v", w.Code, w.Header())
+	}
+}
+
+func TestCORSCnfValidate(t *testing.T) {
+	cnfs := []CORSCnf{
+		{Enable: true},
+		{Enable: true, AllowedOrigins: []string{"^https://(.test$"}},
+		{Enable: true, AllowedOrigins: []string{"*"}, AllowedMethods: []string{"CONNECT"}},
+		{Enable: true, AllowedOrigins: []string{"https://app.example.com", "*"}, AllowCredentials: true},
+	}
+	for _, cnf := range cnfs {
+		if err := cnf.Validate(); err == nil {
+			t.Errorf("Test Failed!, expected an error for %+v", cnf)
+		}
+	}
+	disabled := CORSCnf{}
+	if err := disabled.Validate(); err != nil {
+		t.Errorf("Test Failed!, expected no error, got: %v", err)
+	}
+}
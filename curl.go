@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Curl util constants
+const (
+	curlCommand = "curl"
+	curlMsg     = "Reproduce the request with : %s"
+)
+
+// shellQuote quotes a string so that it can be safely used as a shell argument
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// Curl renders the request as a curl command which can be copied and pasted to reproduce the request
+// The method, url, headers, credentials, proxy and body of the request are included in the command
+// Secrets in the headers, credentials and body are masked using the RedactionPolicy of the client
+// The headers and url of the base http request are used if NewRequest was already called
+func (r *Request) Curl() string {
+	policy := r.getClient().getRedactionPolicy()
+	policy.MaxBodySize = 0
+
+	method := r.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	rawUrl := r.Url
+	header := http.Header{}
+	if r.Request != nil {
+		rawUrl = r.Request.URL.String()
+		header = r.Request.Header.Clone()
+	} else {
+		for k, v := range r.Headers {
+			header.Set(k, v)
+		}
+		for _, cookie := range r.Cookies {
+			header.Add("Cookie", cookie.String())
+		}
+	}
+
+	switch {
+	case r.Auth.TokenSource != nil || r.Auth.Token != "":
+		header.Set(authorizationKey, bearerTokenType+" "+redactedValue)
+	case r.Auth.Username != "" && r.Auth.Password != "":
+		header.Del(authorizationKey)
+	}
+
+	args := []string{curlCommand, "-X", method}
+
+	if r.Cnf.SkipTLS {
+		args = append(args, "-k")
+	}
+	if r.Cnf.ProxyEnable {
+		args = append(args, "-x", shellQuote(r.Cnf.GetProxyUrl()))
+	}
+	if r.Auth.TokenSource == nil && r.Auth.Token == "" && r.Auth.Username != "" && r.Auth.Password != "" {
+		if r.Auth.Scheme == DigestAuthScheme {
+			args = append(args, "--digest")
+		}
+		args = append(args, "-u", shellQuote(r.Auth.Username+":"+redactedValue))
+	}
+
+	if r.Body.Multipart != nil {
+		// curl sets the multipart content type including the boundary itself
+		header.Del(contentTypeKey)
+	}
+
+	redacted := policy.RedactHeaders(header)
+	keys := make([]string, 0, len(redacted))
+	for k := range redacted {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range redacted[k] {
+			args = append(args, "-H", shellQuote(k+": "+v))
+		}
+	}
+
+	args = append(args, r.curlBody(policy, header.Get(contentTypeKey))...)
+	args = append(args, shellQuote(rawUrl))
+
+	return strings.Join(args, " ")
+}
+
+// curlBody returns the curl arguments for the body of the request
+func (r *Request) curlBody(policy RedactionPolicy, contentType string) []string {
+	switch {
+	case r.Body.Json != nil:
+		return []string{"--data-raw", shellQuote(policy.RedactBody(r.Body.Json, applicationJsonContentType))}
+	case r.Body.Text != "":
+		return []string{"--data-raw", shellQuote(policy.RedactBody([]byte(r.Body.Text), contentType))}
+	case r.Body.Form != nil:
+		return []string{"--data-raw", shellQuote(policy.RedactBody([]byte(r.Body.Form.Encode()), formContentType))}
+	case r.Body.Multipart != nil:
+		var args []string
+		keys := make([]string, 0, len(r.Body.Multipart.Fields))
+		for k := range r.Body.Multipart.Fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			v := r.Body.Multipart.Fields[k]
+			if policy.isRedactedField(k) {
+				v = redactedValue
+			}
+			args = append(args, "-F", shellQuote(k+"="+v))
+		}
+		for _, f := range r.Body.Multipart.Files {
+			path := f.Path
+			if path == "" {
+				path = "-"
+			}
+			args = append(args, "-F", shellQuote(fmt.Sprintf("%s=@%s", f.FieldName, path)))
+		}
+		return args
+	case r.Body.Reader != nil:
+		return []string{"--data-binary", "@-"}
+	}
+	return nil
+}
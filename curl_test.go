@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestRequestCurl(t *testing.T) {
+	r := Request{
+		Url:     "https://example.com/api",
+		Method:  http.MethodPost,
+		Auth:    Auth{Username: "admin", Password: "secret"},
+		Body:    RequestBody{Json: []byte(`{"name":"it's","password":"secret"}`)},
+		Headers: map[string]string{"X-Api-Key": "key"},
+		Query:   map[string]string{"q": "a b"},
+		Cnf:     HTTPCnf{ProxyEnable: true, ProxyProtocol: "http", ProxyHost: "proxy", ProxyPort: "8080"},
+	}
+	if err := r.NewRequest(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `curl -X POST -x 'http://proxy:8080' -u 'admin:*****' -H 'Accept: application/json' ` +
+		`-H 'Content-Type: application/json' -H 'X-Api-Key: *****' ` +
+		`--data-raw '{"name":"it'\''s","password":"*****"}' 'https://example.com/api?q=a+b'`
+
+	if result := r.Curl(); result != expected {
+		t.Errorf("Test Failed!, expected: %v, got: %v", expected, result)
+	}
+}
+
+func ExampleRequest_Curl() {
+	r := Request{Url: "https://example.com", Method: http.MethodGet, Auth: Auth{Token: "token"}}
+	fmt.Println(r.Curl())
+	// Output: curl -X GET -H 'Authorization: *****' 'https://example.com'
+}
@@ -0,0 +1,337 @@
+package utils
+
+import (
+	"container/list"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Digest authentication util constants
+const (
+	BasicAuthScheme  = "Basic"
+	DigestAuthScheme = "Digest"
+
+	wwwAuthenticateKey    = "WWW-Authenticate"
+	qopAuth               = "auth"
+	md5DigestAlgorithm    = "MD5"
+	sha256DigestAlgorithm = "SHA-256"
+	sessSuffix            = "-sess"
+
+	defaultMaxDigestSessions = 1024
+	digestSessionTTL         = 10 * time.Minute
+
+	invalidAuthSchemeErrMsg       = "Invalid authentication scheme '%s'. Valid values are %v"
+	unsupportedDigestChallengeMsg = "Unsupported digest challenge, algorithm '%s' qop '%s'"
+	digestChallengeMsg            = "Received a digest challenge for realm '%s', retrying the request with digest authentication"
+)
+
+var (
+	validAuthSchemes = []string{BasicAuthScheme, DigestAuthScheme}
+)
+
+// InvalidAuthSchemeError represents an error when the authentication scheme is not valid
+type InvalidAuthSchemeError string
+
+// Error returns the formatted InvalidAuthSchemeError
+func (ias InvalidAuthSchemeError) Error() string {
+	return fmt.Sprintf(invalidAuthSchemeErrMsg, string(ias), validAuthSchemes)
+}
+
+// UnsupportedDigestChallengeError represents an error when the digest challenge of the remote server is not supported
+type UnsupportedDigestChallengeError struct {
+	Algorithm string
+	Qop       string
+}
+
+// Error returns the formatted UnsupportedDigestChallengeError
+func (udc UnsupportedDigestChallengeError) Error() string {
+	return fmt.Sprintf(unsupportedDigestChallengeMsg, udc.Algorithm, udc.Qop)
+}
+
+// digestChallenge represents the parameters of a WWW-Authenticate digest challenge
+type digestChallenge struct {
+	Realm     string
+	Nonce     string
+	Opaque    string
+	Algorithm string
+	Qop       string
+}
+
+// parseDigestChallenge parses the digest challenge from the values of the WWW-Authenticate header
+// The method returns false if there is no digest challenge
+func parseDigestChallenge(values []string) (*digestChallenge, bool) {
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		scheme := value
+		if i := strings.IndexAny(value, " \t"); i != -1 {
+			scheme = value[:i]
+		}
+		if !strings.EqualFold(scheme, DigestAuthScheme) {
+			continue
+		}
+		params := parseAuthParams(value[len(scheme):])
+		c := &digestChallenge{
+			Realm:     params["realm"],
+			Nonce:     params["nonce"],
+			Opaque:    params["opaque"],
+			Algorithm: params["algorithm"],
+			Qop:       params["qop"],
+		}
+		if c.Algorithm == "" {
+			c.Algorithm = md5DigestAlgorithm
+		}
+		return c, c.Nonce != ""
+	}
+	return nil, false
+}
+
+// parseAuthParams parses the comma separated key=value parameters of an authentication header
+// Values may be quoted strings which contain commas and escaped quotes
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		eq := strings.IndexByte(s, '=')
+		if eq == -1 {
+			return params
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimLeft(s[eq+1:], " \t")
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+			}
+			value = b.String()
+			if i < len(s) {
+				i++
+			}
+			s = s[i:]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end == -1 {
+				end = len(s)
+			}
+			value = strings.TrimSpace(s[:end])
+			s = s[end:]
+		}
+		params[key] = value
+	}
+}
+
+// digestSession represents the digest challenge used for a host and user along with the nonce count
+type digestSession struct {
+	key       string
+	challenge *digestChallenge
+	created   time.Time
+	nc        int
+	mu        sync.Mutex
+}
+
+// digestSessionStore stores the digest sessions per host and user so that the nonce of a challenge can be
+// reused by the following requests, sessions expire after the digestSessionTTL and the store keeps at most
+// max sessions by discarding the least recently used sessions
+type digestSessionStore struct {
+	sessions map[string]*list.Element
+	lru      *list.List
+	max      int
+	mu       sync.Mutex
+}
+
+// newDigestSessionStore returns a new digestSessionStore which keeps at most max sessions
+func newDigestSessionStore(max int) *digestSessionStore {
+	if max <= 0 {
+		max = defaultMaxDigestSessions
+	}
+	return &digestSessionStore{sessions: make(map[string]*list.Element), lru: list.New(), max: max}
+}
+
+// get returns the session for the key, the method returns nil if there is no session or the session expired
+func (dss *digestSessionStore) get(key string) *digestSession {
+	dss.mu.Lock()
+	defer dss.mu.Unlock()
+
+	e, ok := dss.sessions[key]
+	if !ok {
+		return nil
+	}
+	s := e.Value.(*digestSession)
+	if time.Since(s.created) > digestSessionTTL {
+		dss.lru.Remove(e)
+		delete(dss.sessions, key)
+		return nil
+	}
+	dss.lru.MoveToFront(e)
+	return s
+}
+
+// set stores a new session for the challenge
+func (dss *digestSessionStore) set(key string, c *digestChallenge) *digestSession {
+	dss.mu.Lock()
+	defer dss.mu.Unlock()
+
+	if e, ok := dss.sessions[key]; ok {
+		dss.lru.Remove(e)
+	}
+	s := &digestSession{key: key, challenge: c, created: time.Now()}
+	dss.sessions[key] = dss.lru.PushFront(s)
+	for len(dss.sessions) > dss.max {
+		oldest := dss.lru.Back()
+		dss.lru.Remove(oldest)
+		delete(dss.sessions, oldest.Value.(*digestSession).key)
+	}
+	return s
+}
+
+// digestHash returns a hash function for the digest algorithm
+func digestHash(algorithm string) (func() hash.Hash, bool) {
+	switch strings.ToUpper(strings.TrimSuffix(strings.ToLower(algorithm), sessSuffix)) {
+	case md5DigestAlgorithm:
+		return md5.New, true
+	case sha256DigestAlgorithm:
+		return sha256.New, true
+	}
+	return nil, false
+}
+
+// hashHex returns the hex encoded hash of the values joined by colons
+func hashHex(h func() hash.Hash, values ...string) string {
+	d := h()
+	d.Write([]byte(strings.Join(values, ":")))
+	return hex.EncodeToString(d.Sum(nil))
+}
+
+// newCnonce returns a new random client nonce
+func newCnonce() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// digestAuthorization returns the value of the Authorization header for the request
+// The method returns an error if the algorithm or the qop of the challenge is not supported
+func digestAuthorization(c *digestChallenge, username, password, method, uri string, nc int, cnonce string) (string, error) {
+	h, ok := digestHash(c.Algorithm)
+	if !ok {
+		return "", UnsupportedDigestChallengeError{Algorithm: c.Algorithm, Qop: c.Qop}
+	}
+
+	qop := ""
+	if c.Qop != "" {
+		for _, q := range strings.Split(c.Qop, ",") {
+			if strings.TrimSpace(q) == qopAuth {
+				qop = qopAuth
+			}
+		}
+		if qop == "" {
+			return "", UnsupportedDigestChallengeError{Algorithm: c.Algorithm, Qop: c.Qop}
+		}
+	}
+
+	ha1 := hashHex(h, username, c.Realm, password)
+	if strings.HasSuffix(strings.ToLower(c.Algorithm), sessSuffix) {
+		ha1 = hashHex(h, ha1, c.Nonce, cnonce)
+	}
+	ha2 := hashHex(h, method, uri)
+
+	ncValue := fmt.Sprintf("%08x", nc)
+
+	var response string
+	if qop == "" {
+		response = hashHex(h, ha1, c.Nonce, ha2)
+	} else {
+		response = hashHex(h, ha1, c.Nonce, ncValue, cnonce, qop, ha2)
+	}
+
+	params := []string{
+		fmt.Sprintf(`username="%s"`, escapeQuotes(username)),
+		fmt.Sprintf(`realm="%s"`, escapeQuotes(c.Realm)),
+		fmt.Sprintf(`nonce="%s"`, escapeQuotes(c.Nonce)),
+		fmt.Sprintf(`uri="%s"`, escapeQuotes(uri)),
+		fmt.Sprintf(`algorithm=%s`, c.Algorithm),
+		fmt.Sprintf(`response="%s"`, response),
+	}
+	if qop != "" {
+		params = append(params, "qop="+qop, "nc="+ncValue, fmt.Sprintf(`cnonce="%s"`, cnonce))
+	}
+	if c.Opaque != "" {
+		params = append(params, fmt.Sprintf(`opaque="%s"`, escapeQuotes(c.Opaque)))
+	}
+
+	return DigestAuthScheme + " " + strings.Join(params, ", "), nil
+}
+
+// authorize sets the digest Authorization header on the request using the next nonce count of the session
+func (s *digestSession) authorize(req *http.Request, auth Auth) error {
+	s.mu.Lock()
+	s.nc++
+	nc := s.nc
+	s.mu.Unlock()
+
+	header, err := digestAuthorization(s.challenge, auth.Username, auth.Password, req.Method, req.URL.RequestURI(), nc, newCnonce())
+	if err != nil {
+		return err
+	}
+	req.Header.Set(authorizationKey, header)
+	return nil
+}
+
+// digestRoundTrip makes the request using digest authentication
+// The nonce of a previous challenge for the same host and user is reused from the sessions, when the remote
+// server responds with a new digest challenge the request is retried once if the request body can be replayed
+func digestRoundTrip(next http.RoundTripper, req *http.Request, auth Auth, sessions *digestSessionStore) (*http.Response, error) {
+	key := req.URL.Host + " " + auth.Username
+
+	authReq := req.Clone(req.Context())
+	if s := sessions.get(key); s != nil {
+		if err := s.authorize(authReq, auth); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := next.RoundTrip(authReq)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	c, ok := parseDigestChallenge(resp.Header[http.CanonicalHeaderKey(wwwAuthenticateKey)])
+	if !ok {
+		return resp, nil
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return resp, nil
+	}
+
+	log := LogFormatter{Msg: fmt.Sprintf(digestChallengeMsg, c.Realm)}
+	log.Debug().Println(log.Out)
+
+	resp.Body.Close()
+
+	retryReq := req.Clone(req.Context())
+	if err := sessions.set(key, c).authorize(retryReq, auth); err != nil {
+		return nil, err
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retryReq.Body = body
+	}
+
+	return next.RoundTrip(retryReq)
+}
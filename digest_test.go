@@ -0,0 +1,175 @@
+package utils
+
+import (
+	"crypto/md5"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDigestAuthorization(t *testing.T) {
+	// example from RFC 2617 section 3.5
+	c := &digestChallenge{
+		Realm:     "testrealm@host.com",
+		Nonce:     "dcd98b7102dd2f0e8b11d0f600bfb0c093",
+		Opaque:    "5ccc069c403ebaf9f0171e9517f40e41",
+		Algorithm: md5DigestAlgorithm,
+		Qop:       "auth,auth-int",
+	}
+	header, err := digestAuthorization(c, "Mufasa", "Circle Of Life", http.MethodGet, "/dir/index.html", 1, "0a4f113b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := parseAuthParams(strings.TrimPrefix(header, DigestAuthScheme))
+	expected := map[string]string{
+		"response": "6629fae49393a05397450978507c4ef1",
+		"nc":       "00000001",
+		"qop":      qopAuth,
+		"opaque":   c.Opaque,
+		"uri":      "/dir/index.html",
+	}
+	for k, v := range expected {
+		if params[k] != v {
+			t.Errorf("Test Failed!, %s expected: %v, got: %v", k, v, params[k])
+		}
+	}
+
+	c.Qop = "auth-int"
+	if _, err := digestAuthorization(c, "Mufasa", "Circle Of Life", http.MethodGet, "/", 1, "0a4f113b"); err == nil {
+		t.Error("Test Failed!, expected an UnsupportedDigestChallengeError")
+	}
+}
+
+func TestParseDigestChallenge(t *testing.T) {
+	c, ok := parseDigestChallenge([]string{
+		`Basic realm="basic"`,
+		`Digest realm="a, \"quoted\" realm", qop="auth", nonce="abc"`,
+	})
+	if !ok {
+		t.Fatal("Test Failed!, expected a digest challenge")
+	}
+	if c.Realm != `a, "quoted" realm` || c.Nonce != "abc" || c.Qop != qopAuth || c.Algorithm != md5DigestAlgorithm {
+		t.Errorf("Test Failed!, got: %+v", c)
+	}
+
+	if _, ok := parseDigestChallenge([]string{`Basic realm="basic"`}); ok {
+		t.Error("Test Failed!, expected no digest challenge")
+	}
+	if _, ok := parseDigestChallenge([]string{`Digestive realm="test", nonce="abc"`}); ok {
+		t.Error("Test Failed!, expected no digest challenge")
+	}
+	if c, ok := parseDigestChallenge([]string{`digest realm="test", nonce="abc"`}); !ok || c.Realm != "test" {
+		t.Errorf("Test Failed!, expected a digest challenge, got: %+v", c)
+	}
+}
+
+func TestDigestSessionStore(t *testing.T) {
+	store := newDigestSessionStore(2)
+	for _, key := range []string{"a", "b", "c"} {
+		store.set(key, &digestChallenge{Nonce: key})
+	}
+	if store.get("a") != nil || store.get("b") == nil || store.get("c") == nil {
+		t.Errorf("Test Failed!, expected the least recently used session to be discarded")
+	}
+
+	store.get("c").created = time.Now().Add(-digestSessionTTL - time.Second)
+	if store.get("c") != nil {
+		t.Errorf("Test Failed!, expected the session to expire")
+	}
+	if len(store.sessions) != 1 || store.lru.Len() != 1 {
+		t.Errorf("Test Failed!, expected: %v, got: %v", 1, len(store.sessions))
+	}
+}
+
+// digestServer returns a test server which requires digest authentication and records the nonce counts
+func digestServer(username, password string) (*httptest.Server, *[]string) {
+	const realm, nonce = "test", "nonce-1"
+	var (
+		mu  sync.Mutex
+		ncs []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get(authorizationKey)
+		if !strings.HasPrefix(header, DigestAuthScheme+" ") {
+			w.Header().Set(wwwAuthenticateKey, `Digest realm="`+realm+`", qop="auth", nonce="`+nonce+`", opaque="op"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		p := parseAuthParams(header[len(DigestAuthScheme):])
+		ha1 := hashHex(md5.New, username, realm, password)
+		ha2 := hashHex(md5.New, r.Method, p["uri"])
+		if p["uri"] != r.URL.RequestURI() || p["opaque"] != "op" ||
+			p["response"] != hashHex(md5.New, ha1, nonce, p["nc"], p["cnonce"], p["qop"], ha2) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		ncs = append(ncs, p["nc"])
+		mu.Unlock()
+		_, _ = w.Write([]byte("ok"))
+	}))
+	return server, &ncs
+}
+
+func TestDigestAuth(t *testing.T) {
+	server, ncs := digestServer("user", "pass")
+	defer server.Close()
+
+	client := &Client{}
+	for _, body := range []string{"", "first", "second"} {
+		r := Request{
+			Url:    server.URL + "/resource?x=1",
+			Method: http.MethodPost,
+			Body:   RequestBody{Text: body},
+			Auth:   Auth{Username: "user", Password: "pass", Scheme: DigestAuthScheme},
+			Client: client,
+		}
+		if err := r.NewRequest(); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.HttpRequest(); err != nil {
+			t.Fatal(err)
+		}
+		if r.Result.StatusCode != http.StatusOK {
+			t.Errorf("Test Failed!, expected: %v, got: %v", http.StatusOK, r.Result.StatusCode)
+		}
+	}
+
+	// the challenges are not shared between clients
+	r := Request{Url: server.URL, Method: http.MethodGet, Auth: Auth{Username: "user", Password: "pass", Scheme: DigestAuthScheme}, Client: &Client{}}
+	if err := r.NewRequest(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.HttpRequest(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the nonce of the first challenge is reused with an incremented nonce count
+	expected := []string{"00000001", "00000002", "00000003", "00000001"}
+	if strings.Join(*ncs, ",") != strings.Join(expected, ",") {
+		t.Errorf("Test Failed!, expected: %v, got: %v", expected, *ncs)
+	}
+
+	r = Request{Url: server.URL, Method: http.MethodGet, Auth: Auth{Username: "user", Password: "wrong", Scheme: DigestAuthScheme}}
+	if err := r.NewRequest(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.HttpRequest(); err != nil {
+		t.Fatal(err)
+	}
+	if r.Result.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Test Failed!, expected: %v, got: %v", http.StatusUnauthorized, r.Result.StatusCode)
+	}
+}
+
+func TestInvalidAuthScheme(t *testing.T) {
+	r := Request{Url: "http://localhost", Method: http.MethodGet, Auth: Auth{Username: "user", Password: "pass", Scheme: "NTLM"}}
+	if err := r.NewRequest(); err == nil {
+		t.Error("Test Failed!, expected an InvalidAuthSchemeError")
+	} else if _, ok := err.(InvalidAuthSchemeError); !ok {
+		t.Errorf("Test Failed!, expected: InvalidAuthSchemeError, got: %T", err)
+	}
+}
@@ -0,0 +1,289 @@
+package utils
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Download util constants
+const (
+	rangeKey             = "Range"
+	contentRangeKey      = "Content-Range"
+	partialFileExtension = ".part"
+
+	sha256Algorithm = "sha256"
+	sha1Algorithm   = "sha1"
+	sha512Algorithm = "sha512"
+	md5Algorithm    = "md5"
+
+	checksumMismatchErrMsg         = "Checksum mismatch for file '%s' : expected %s, got %s"
+	invalidChecksumAlgorithmErrMsg = "Invalid checksum algorithm '%s'. Valid values are %v"
+	downloadResumeMsg              = "Resuming the download of '%s' from byte %d"
+	downloadRestartMsg             = "The server does not support resuming downloads, restarting the download of '%s'"
+	downloadRangeMismatchMsg       = "The server did not resume the download of '%s' from byte %d, restarting the download"
+	downloadCompletedMsg           = "Download of '%s' completed, %d bytes written to '%s'"
+	fileRenameErrMsg               = "Unable to rename file '%s' to '%s' : %v"
+)
+
+var (
+	validChecksumAlgorithms = []string{sha256Algorithm, sha1Algorithm, sha512Algorithm, md5Algorithm}
+)
+
+// ChecksumMismatchError represents an error when the checksum of a downloaded file does not match the expected checksum
+type ChecksumMismatchError struct {
+	File     string
+	Expected string
+	Actual   string
+}
+
+// Error returns the formatted ChecksumMismatchError
+func (cm ChecksumMismatchError) Error() string {
+	return fmt.Sprintf(checksumMismatchErrMsg, cm.File, cm.Expected, cm.Actual)
+}
+
+// InvalidChecksumAlgorithmError represents an error when the checksum algorithm is not valid
+type InvalidChecksumAlgorithmError string
+
+// Error returns the formatted InvalidChecksumAlgorithmError
+func (ica InvalidChecksumAlgorithmError) Error() string {
+	return fmt.Sprintf(invalidChecksumAlgorithmErrMsg, string(ica), validChecksumAlgorithms)
+}
+
+// FileRenameError represents an error when the code is not able to rename a file
+type FileRenameError struct {
+	From string
+	To   string
+	Err  error
+}
+
+// Error returns the formatted FileRenameError
+func (fr FileRenameError) Error() string {
+	return fmt.Sprintf(fileRenameErrMsg, fr.From, fr.To, fr.Err)
+}
+
+// ProgressFunc is called while a download is in progress with the number of bytes written so far
+// and the total size of the file, total is -1 if the size is not known
+type ProgressFunc func(written, total int64)
+
+// Download represents the download of a remote file to a file on disk
+// The file is downloaded to Path with a .part extension and renamed to Path once the download is complete
+// If Resume is set and a partial file exists the download continues from the end of the partial file
+// using a HTTP Range request
+// If Checksum is set the downloaded file is verified using the ChecksumAlgorithm, which defaults to sha256
+type Download struct {
+	Url               string
+	Path              string
+	Auth              Auth
+	Cnf               HTTPCnf
+	Ctx               context.Context
+	Client            *Client
+	Resume            bool
+	Checksum          string
+	ChecksumAlgorithm string
+	Progress          ProgressFunc
+}
+
+// DownloadFile downloads the remote file from url to the filePath using the global http configuration
+// A partial download of the file is resumed if the server supports range requests
+// The method returns an error if the download fails
+func DownloadFile(url, filePath string) error {
+	d := Download{Url: url, Path: filePath, Cnf: getHTTPCnf(), Resume: true}
+	return d.Do()
+}
+
+// newHash returns a new hash for the checksum algorithm
+func newHash(algorithm string) (hash.Hash, error) {
+	switch strings.ToLower(algorithm) {
+	case "", sha256Algorithm:
+		return sha256.New(), nil
+	case sha1Algorithm:
+		return sha1.New(), nil
+	case sha512Algorithm:
+		return sha512.New(), nil
+	case md5Algorithm:
+		return md5.New(), nil
+	default:
+		return nil, InvalidChecksumAlgorithmError(algorithm)
+	}
+}
+
+// progressWriter calls the progress function for every write
+type progressWriter struct {
+	written  int64
+	total    int64
+	progress ProgressFunc
+}
+
+// Write counts the bytes written and reports the progress
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	pw.written += int64(len(p))
+	if pw.progress != nil {
+		pw.progress(pw.written, pw.total)
+	}
+	return len(p), nil
+}
+
+// Do downloads the remote file
+// The method returns an error if the request fails, the server responds with a non 2xx status code,
+// the file cannot be written or if the checksum of the downloaded file does not match
+func (d *Download) Do() error {
+	return d.do(d.Resume)
+}
+
+// do downloads the remote file, the partial file is resumed if resume is set
+func (d *Download) do(resume bool) error {
+	h, err := newHash(d.ChecksumAlgorithm)
+	if err != nil {
+		return err
+	}
+
+	partFile := d.Path + partialFileExtension
+
+	var offset int64
+	if resume {
+		if fi, err := os.Stat(partFile); err == nil {
+			offset = fi.Size()
+		}
+	}
+
+	r := Request{Url: d.Url, Method: http.MethodGet, Auth: d.Auth, Cnf: d.Cnf, Ctx: d.Ctx, Client: d.Client, Stream: true}
+	if err := r.NewRequest(); err != nil {
+		return err
+	}
+	// the file is downloaded as it is so that the size is known and the download can be resumed
+	r.Request.Header.Set(acceptEncodingKey, identityEncoding)
+	if offset > 0 {
+		r.Request.Header.Set(rangeKey, fmt.Sprintf("bytes=%d-", offset))
+		log := LogFormatter{Msg: fmt.Sprintf(downloadResumeMsg, d.Url, offset)}
+		log.Debug().Println(log.Out)
+	}
+
+	if err := r.HttpRequest(); err != nil {
+		return err
+	}
+	defer r.Result.Stream.Close()
+
+	if offset > 0 && r.Result.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		// the partial file is not valid anymore, start over
+		r.Result.Stream.Close()
+		if err := os.Remove(partFile); err != nil && !os.IsNotExist(err) {
+			return FileWriteError{File: partFile, Err: err}
+		}
+		return d.do(false)
+	}
+
+	if offset > 0 && r.Result.StatusCode == http.StatusPartialContent {
+		if start, ok := contentRangeStart(r.Result.Header); !ok || start != offset {
+			// appending a range which does not start at the end of the partial file corrupts the file
+			log := LogFormatter{Msg: fmt.Sprintf(downloadRangeMismatchMsg, d.Url, offset)}
+			log.Debug().Println(log.Out)
+			r.Result.Stream.Close()
+			return d.do(false)
+		}
+	}
+
+	if !r.Result.IsSuccess() {
+		return HTTPStatusError{StatusCode: r.Result.StatusCode}
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if offset > 0 && r.Result.StatusCode == http.StatusPartialContent {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		if err := hashFile(h, partFile); err != nil {
+			return err
+		}
+	} else if offset > 0 {
+		log := LogFormatter{Msg: fmt.Sprintf(downloadRestartMsg, d.Url)}
+		log.Debug().Println(log.Out)
+		offset = 0
+	}
+
+	f, err := os.OpenFile(partFile, flags, 0644)
+	if err != nil {
+		return FileOpenError{File: partFile, Err: err}
+	}
+
+	pw := &progressWriter{written: offset, total: contentTotal(r.Result.Header, offset), progress: d.Progress}
+	_, err = io.Copy(io.MultiWriter(f, h, pw), r.Result.Stream)
+	if cErr := f.Close(); err == nil && cErr != nil {
+		err = cErr
+	}
+	if err != nil {
+		return FileWriteError{File: partFile, Err: err}
+	}
+
+	if d.Checksum != "" {
+		actual := hex.EncodeToString(h.Sum(nil))
+		if !strings.EqualFold(actual, d.Checksum) {
+			_ = os.Remove(partFile)
+			return ChecksumMismatchError{File: d.Path, Expected: d.Checksum, Actual: actual}
+		}
+	}
+
+	if err := os.Rename(partFile, d.Path); err != nil {
+		return FileRenameError{From: partFile, To: d.Path, Err: err}
+	}
+
+	log := LogFormatter{Msg: fmt.Sprintf(downloadCompletedMsg, d.Url, pw.written, d.Path)}
+	log.Debug().Println(log.Out)
+
+	return nil
+}
+
+// hashFile writes the contents of the file into the hash
+func hashFile(h hash.Hash, file string) error {
+	f, err := OpenFile(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return FileReadError{File: file, Err: err}
+	}
+	return nil
+}
+
+// contentRangeStart returns the first byte position of a Content-Range header like "bytes 100-199/200"
+// The method returns false if the header is missing or not valid
+func contentRangeStart(header http.Header) (int64, bool) {
+	cr := strings.TrimSpace(header.Get(contentRangeKey))
+	if !strings.HasPrefix(cr, "bytes ") {
+		return 0, false
+	}
+	i := strings.Index(cr, "-")
+	if i == -1 {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(strings.TrimSpace(cr[len("bytes "):i]), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return start, true
+}
+
+// contentTotal returns the total size of the remote file based on the Content-Range or Content-Length headers
+// The method returns -1 if the size is not known
+func contentTotal(header http.Header, offset int64) int64 {
+	if cr := header.Get(contentRangeKey); cr != "" {
+		if i := strings.LastIndex(cr, "/"); i != -1 {
+			if total, err := strconv.ParseInt(cr[i+1:], 10, 64); err == nil {
+				return total
+			}
+		}
+	}
+	if cl, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64); err == nil {
+		return cl + offset
+	}
+	return -1
+}
@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDownloadResume(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	sum := sha256.Sum256(content)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "file.bin")
+	if err := WriteFile(file+partialFileExtension, content[:4000]); err != nil {
+		t.Fatal(err)
+	}
+
+	var written, total int64
+	d := Download{
+		Url:      server.URL,
+		Path:     file,
+		Resume:   true,
+		Checksum: hex.EncodeToString(sum[:]),
+		Progress: func(w, t int64) {
+			written, total = w, t
+		},
+	}
+	if err := d.Do(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, content) {
+		t.Errorf("Test Failed!, expected: %v bytes, got: %v bytes", len(content), len(data))
+	}
+	if written != int64(len(content)) || total != int64(len(content)) {
+		t.Errorf("Test Failed!, expected: %v/%v, got: %v/%v", len(content), len(content), written, total)
+	}
+	if FileExists(file + partialFileExtension) {
+		t.Errorf("Test Failed!, expected the partial file to be renamed")
+	}
+}
+
+func TestDownloadChecksumMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "file.txt")
+	d := Download{Url: server.URL, Path: file, Checksum: "invalid"}
+
+	if _, ok := d.Do().(ChecksumMismatchError); !ok {
+		t.Errorf("Test Failed!, expected: %v", "ChecksumMismatchError")
+	}
+	if FileExists(file) {
+		t.Errorf("Test Failed!, expected the file not to exist")
+	}
+}
+
+func TestDownloadRangeMismatch(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 100)
+
+	tests := []struct {
+		name   string
+		status int
+		start  int
+	}{
+		{"range not satisfiable", http.StatusRequestedRangeNotSatisfiable, 0},
+		{"other range", http.StatusPartialContent, 200},
+		{"no content range", http.StatusPartialContent, -1},
+	}
+
+	for _, test := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get(rangeKey) == "" {
+				_, _ = w.Write(content)
+				return
+			}
+			start := test.start
+			if start >= 0 {
+				w.Header().Set(contentRangeKey, fmt.Sprintf("bytes %d-%d/%d", start, len(content)-1, len(content)))
+			} else {
+				start = 400
+			}
+			w.WriteHeader(test.status)
+			if test.status == http.StatusPartialContent {
+				_, _ = w.Write(content[start:])
+			}
+		}))
+
+		dir, err := ioutil.TempDir("", "download")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		file := filepath.Join(dir, "file.bin")
+		if err := WriteFile(file+partialFileExtension, content[:400]); err != nil {
+			t.Fatal(err)
+		}
+
+		d := Download{Url: server.URL, Path: file, Resume: true}
+		if err := d.Do(); err != nil {
+			t.Errorf("Test Failed!, %s: expected no error, got: %v", test.name, err)
+		}
+		if !d.Resume {
+			t.Errorf("Test Failed!, %s: expected the download not to be modified", test.name)
+		}
+		if data, _ := ReadFile(file); !bytes.Equal(data, content) {
+			t.Errorf("Test Failed!, %s: expected: %v bytes, got: %v bytes", test.name, len(content), len(data))
+		}
+
+		server.Close()
+		os.RemoveAll(dir)
+	}
+}
@@ -3,7 +3,9 @@ module github.com/atselvan/goutils
 go 1.13
 
 require (
+	github.com/andybalholm/brotli v1.0.4
 	github.com/gin-gonic/gin v1.6.3
+	github.com/go-playground/validator/v10 v10.2.0
 	github.com/manifoldco/promptui v0.7.0
 	gopkg.in/yaml.v2 v2.2.8
 )
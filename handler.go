@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler util constants
+const (
+	recoveredPanicMsg = "Recovered from panic : %v"
+)
+
+// NoRouteHandler is a gin handler which responds with 404 Path Not Found for the paths without a route
+func NoRouteHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.IndentedJSON(http.StatusNotFound, ErrResponse{Error: PathNotFoundMsg})
+		log := LogFormatter{Request: ctx.Request, StatusCode: http.StatusNotFound, Msg: PathNotFoundMsg}
+		log.Info().Println(log.Out)
+	}
+}
+
+// NoMethodHandler is a gin handler which responds with 405 Method Not Allowed for the paths which
+// have a route but not for the request method
+// The handler is only called if HandleMethodNotAllowed is set on the gin engine
+func NoMethodHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.IndentedJSON(http.StatusMethodNotAllowed, ErrResponse{Error: MethodNotAllowedMsg})
+		log := LogFormatter{Request: ctx.Request, StatusCode: http.StatusMethodNotAllowed, Msg: MethodNotAllowedMsg}
+		log.Info().Println(log.Out)
+	}
+}
+
+// Recovery is a gin middleware which recovers from panics in the handlers
+// The panic and the stack trace are logged at error level and the client receives a 500 Internal Server Error
+// without any details of the panic
+func Recovery() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		defer func() {
+			if err := recover(); err != nil {
+				log := LogFormatter{Request: ctx.Request, StatusCode: http.StatusInternalServerError, Msg: fmt.Sprintf(recoveredPanicMsg, err)}
+				logger := log.Error()
+				logger.Println(log.Out)
+				logger.Printf("%s", debug.Stack())
+
+				if ctx.Writer.Written() {
+					ctx.Abort()
+					return
+				}
+				ctx.AbortWithStatusJSON(http.StatusInternalServerError, ErrResponse{Error: InternalServerErrMsg})
+			}
+		}()
+		ctx.Next()
+	}
+}
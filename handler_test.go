@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestServerErrorHandlers(t *testing.T) {
+	s, err := NewServer(ServerCnf{Host: "127.0.0.1", Port: "0", LoggerCnf: LoggerCnf{Level: infoLogLevel}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Engine.GET("/ok", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "ok")
+	})
+	s.Engine.GET("/panic", func(ctx *gin.Context) {
+		panic("secret panic details")
+	})
+
+	tests := []struct {
+		method, path string
+		statusCode   int
+		msg          string
+	}{
+		{http.MethodGet, "/ok", http.StatusOK, ""},
+		{http.MethodGet, "/unknown", http.StatusNotFound, PathNotFoundMsg},
+		{http.MethodPost, "/ok", http.StatusMethodNotAllowed, MethodNotAllowedMsg},
+		{http.MethodGet, "/panic", http.StatusInternalServerError, InternalServerErrMsg},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		s.Engine.ServeHTTP(w, httptest.NewRequest(test.method, test.path, nil))
+		if w.Code != test.statusCode {
+			t.Errorf("Test Failed!, %s %s expected: %v, got: %v", test.method, test.path, test.statusCode, w.Code)
+		}
+		if test.msg == "" {
+			continue
+		}
+		if strings.Contains(w.Body.String(), "secret") {
+			t.Errorf("Test Failed!, the panic details were leaked: %s", w.Body.String())
+		}
+		var resp ErrResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Error != test.msg {
+			t.Errorf("Test Failed!, expected: %v, got: %v", test.msg, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Health util constants
+const (
+	HealthzPath = "/healthz"
+	ReadyzPath  = "/readyz"
+
+	healthStatusOk         = "ok"
+	healthStatusFail       = "fail"
+	defaultHealthTimeout   = 5 * time.Second
+	notReadyErrMsg         = "The service is shutting down"
+	healthCheckTimeoutMsg  = "Health check timed out after %v"
+	healthCheckFailedMsg   = "Health check '%s' failed"
+	readinessCheckName     = "readiness"
+	healthCheckPanicErrMsg = "Health check panicked : %v"
+)
+
+// HealthCheckTimeoutError represents an error when a health check does not complete within its timeout
+type HealthCheckTimeoutError time.Duration
+
+// Error returns the formatted HealthCheckTimeoutError
+func (hct HealthCheckTimeoutError) Error() string {
+	return fmt.Sprintf(healthCheckTimeoutMsg, time.Duration(hct))
+}
+
+// HealthCheckPanicError represents an error when a health check panics
+type HealthCheckPanicError struct {
+	Panic interface{}
+}
+
+// Error returns the formatted HealthCheckPanicError
+func (hcp HealthCheckPanicError) Error() string {
+	return fmt.Sprintf(healthCheckPanicErrMsg, hcp.Panic)
+}
+
+// HealthCheck represents a named check of the health of the service or one of its dependencies
+// Check returns an error if the check fails and should return when the context is done
+// Timeout defaults to 5 seconds, the result of the check is reused for CacheTTL if it is set
+// Liveness checks are run for both /healthz and /readyz, the other checks are only run for /readyz
+type HealthCheck struct {
+	Name     string
+	Check    func(ctx context.Context) error
+	Timeout  time.Duration
+	CacheTTL time.Duration
+	Liveness bool
+}
+
+// HTTPHealthCheck returns a check which makes the request and fails if the request fails
+// or the remote server responds with a non 2xx status code
+func HTTPHealthCheck(name string, r Request) HealthCheck {
+	return HealthCheck{
+		Name: name,
+		Check: func(ctx context.Context) error {
+			req := r
+			req.Ctx = ctx
+			if req.Method == "" {
+				req.Method = http.MethodGet
+			}
+			if err := req.NewRequest(); err != nil {
+				return err
+			}
+			if err := req.HttpRequest(); err != nil {
+				return err
+			}
+			return req.Result.StatusError()
+		},
+	}
+}
+
+// FileHealthCheck returns a check which fails if the file does not exist
+func FileHealthCheck(name, path string) HealthCheck {
+	return HealthCheck{
+		Name: name,
+		Check: func(ctx context.Context) error {
+			if !FileExists(path) {
+				return FileNotFoundError(path)
+			}
+			return nil
+		},
+	}
+}
+
+// CheckStatus represents the result of a single health check
+type CheckStatus struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// HealthStatus represents the health of the service
+type HealthStatus struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckStatus `json:"checks,omitempty"`
+}
+
+// cachedCheck holds the last result of a health check
+type cachedCheck struct {
+	status  CheckStatus
+	checked time.Time
+}
+
+// Health runs the registered health checks and serves the liveness and readiness endpoints
+// The service is ready when it is created and becomes not ready when SetReady(false) is called,
+// for example when the server is shutting down
+type Health struct {
+	checks []HealthCheck
+	cache  map[string]cachedCheck
+	ready  int32
+	mu     sync.Mutex
+}
+
+// NewHealth returns a new Health which is ready
+func NewHealth() *Health {
+	return &Health{cache: make(map[string]cachedCheck), ready: 1}
+}
+
+// AddCheck registers the health checks
+func (h *Health) AddCheck(checks ...HealthCheck) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, checks...)
+}
+
+// SetReady sets the readiness of the service
+func (h *Health) SetReady(ready bool) {
+	var value int32
+	if ready {
+		value = 1
+	}
+	atomic.StoreInt32(&h.ready, value)
+}
+
+// IsReady returns true if the service is ready
+func (h *Health) IsReady() bool {
+	return atomic.LoadInt32(&h.ready) == 1
+}
+
+// run runs a single health check or returns its cached result
+func (h *Health) run(ctx context.Context, check HealthCheck) CheckStatus {
+	if check.CacheTTL > 0 {
+		h.mu.Lock()
+		cached, ok := h.cache[check.Name]
+		h.mu.Unlock()
+		if ok && time.Since(cached.checked) < check.CacheTTL {
+			return cached.status
+		}
+	}
+
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- HealthCheckPanicError{Panic: r}
+			}
+		}()
+		done <- check.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = HealthCheckTimeoutError(timeout)
+	}
+
+	status := CheckStatus{Status: healthStatusOk, Duration: time.Since(start).String()}
+	if err != nil {
+		status.Status = healthStatusFail
+		status.Error = err.Error()
+		log := LogFormatter{Msg: fmt.Sprintf(healthCheckFailedMsg, check.Name), ErrMsg: err}
+		log.Warn().Println(log.Out)
+	}
+
+	if check.CacheTTL > 0 {
+		h.mu.Lock()
+		h.cache[check.Name] = cachedCheck{status: status, checked: time.Now()}
+		h.mu.Unlock()
+	}
+
+	return status
+}
+
+// Check runs the liveness checks, or all the checks when readiness is true, in parallel and returns the health
+// of the service, the service is not healthy if a check fails or, for readiness, when the service is not ready
+func (h *Health) Check(ctx context.Context, readiness bool) HealthStatus {
+	h.mu.Lock()
+	var checks []HealthCheck
+	for _, check := range h.checks {
+		if readiness || check.Liveness {
+			checks = append(checks, check)
+		}
+	}
+	h.mu.Unlock()
+
+	statuses := make([]CheckStatus, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+			statuses[i] = h.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	health := HealthStatus{Status: healthStatusOk, Checks: make(map[string]CheckStatus, len(checks)+1)}
+	for i, check := range checks {
+		health.Checks[check.Name] = statuses[i]
+		if statuses[i].Status != healthStatusOk {
+			health.Status = healthStatusFail
+		}
+	}
+	if readiness && !h.IsReady() {
+		health.Status = healthStatusFail
+		health.Checks[readinessCheckName] = CheckStatus{Status: healthStatusFail, Error: notReadyErrMsg}
+	}
+
+	return health
+}
+
+// public returns a copy of the health without the errors of the checks, which can contain internal urls
+// and error details, the errors are logged when the checks fail
+func (hs HealthStatus) public() HealthStatus {
+	out := HealthStatus{Status: hs.Status, Checks: make(map[string]CheckStatus, len(hs.Checks))}
+	for name, check := range hs.Checks {
+		if name != readinessCheckName {
+			check.Error = ""
+		}
+		out.Checks[name] = check
+	}
+	return out
+}
+
+// handler returns a gin handler which responds with the health of the service as JSON
+// The status code is 200 OK if the service is healthy and 503 Service Unavailable otherwise
+// The errors of the failed checks are not included in the response
+func (h *Health) handler(readiness bool) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		health := h.Check(ctx.Request.Context(), readiness).public()
+		status := http.StatusOK
+		if health.Status != healthStatusOk {
+			status = http.StatusServiceUnavailable
+		}
+		ctx.IndentedJSON(status, health)
+	}
+}
+
+// LivenessHandler returns a gin handler which runs the liveness checks
+func (h *Health) LivenessHandler() gin.HandlerFunc {
+	return h.handler(false)
+}
+
+// ReadinessHandler returns a gin handler which runs all the checks and fails when the service is not ready
+func (h *Health) ReadinessHandler() gin.HandlerFunc {
+	return h.handler(true)
+}
+
+// Register registers the liveness handler on /healthz and the readiness handler on /readyz
+func (h *Health) Register(routes gin.IRoutes) {
+	routes.GET(HealthzPath, h.LivenessHandler())
+	routes.GET(ReadyzPath, h.ReadinessHandler())
+}
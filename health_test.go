@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getHealth calls the health endpoint and returns the status code and the decoded health
+func getHealth(t *testing.T, engine *gin.Engine, path string) (int, HealthStatus) {
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	var health HealthStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &health); err != nil {
+		t.Fatal(err)
+	}
+	return w.Code, health
+}
+
+func TestHealth(t *testing.T) {
+	dependency := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer dependency.Close()
+
+	var calls int32
+	h := NewHealth()
+	h.AddCheck(
+		HTTPHealthCheck("dependency", Request{Url: dependency.URL}),
+		FileHealthCheck("config", "health.go"),
+		HealthCheck{Name: "custom", Liveness: true, CacheTTL: time.Minute, Check: func(ctx context.Context) error {
+			atomic.AddInt32(&calls, 1)
+			return nil
+		}},
+	)
+
+	gin.SetMode(gin.ReleaseMode)
+	engine := gin.New()
+	h.Register(engine)
+
+	code, health := getHealth(t, engine, HealthzPath)
+	if code != http.StatusOK || len(health.Checks) != 1 || health.Checks["custom"].Status != healthStatusOk {
+		t.Errorf("Test Failed!, expected only the liveness check to pass, got: %v %+v", code, health)
+	}
+
+	code, health = getHealth(t, engine, ReadyzPath)
+	if code != http.StatusOK || health.Status != healthStatusOk || len(health.Checks) != 3 {
+		t.Errorf("Test Failed!, expected all the checks to pass, got: %v %+v", code, health)
+	}
+	if calls != 1 {
+		t.Errorf("Test Failed!, expected the custom check to be cached, got %v calls", calls)
+	}
+
+	h.SetReady(false)
+	code, health = getHealth(t, engine, ReadyzPath)
+	if code != http.StatusServiceUnavailable || health.Checks[readinessCheckName].Status != healthStatusFail {
+		t.Errorf("Test Failed!, expected the service to be not ready, got: %v %+v", code, health)
+	}
+	if code, _ := getHealth(t, engine, HealthzPath); code != http.StatusOK {
+		t.Errorf("Test Failed!, expected the service to be alive, got: %v", code)
+	}
+}
+
+func TestHealthCheckFailures(t *testing.T) {
+	h := NewHealth()
+	h.AddCheck(
+		FileHealthCheck("missing", "missing.file"),
+		HealthCheck{Name: "slow", Timeout: 10 * time.Millisecond, Check: func(ctx context.Context) error {
+			<-ctx.Done()
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		}},
+		HealthCheck{Name: "error", Check: func(ctx context.Context) error {
+			return errors.New("down")
+		}},
+		HealthCheck{Name: "panic", Check: func(ctx context.Context) error {
+			panic("boom")
+		}},
+	)
+
+	health := h.Check(context.Background(), true)
+	if health.Status != healthStatusFail {
+		t.Errorf("Test Failed!, expected: %v, got: %v", healthStatusFail, health.Status)
+	}
+	expected := map[string]string{
+		"missing": FileNotFoundError("missing.file").Error(),
+		"slow":    HealthCheckTimeoutError(10 * time.Millisecond).Error(),
+		"error":   "down",
+		"panic":   HealthCheckPanicError{Panic: "boom"}.Error(),
+	}
+	for name, msg := range expected {
+		if health.Checks[name].Status != healthStatusFail || health.Checks[name].Error != msg {
+			t.Errorf("Test Failed!, %s expected: %v, got: %+v", name, msg, health.Checks[name])
+		}
+	}
+}
+
+func TestHealthHandlerHidesErrors(t *testing.T) {
+	h := NewHealth()
+	h.AddCheck(HealthCheck{Name: "database", Liveness: true, Check: func(ctx context.Context) error {
+		return errors.New("dial tcp 10.0.0.5:5432: connection refused")
+	}})
+
+	gin.SetMode(gin.ReleaseMode)
+	engine := gin.New()
+	h.Register(engine)
+
+	code, health := getHealth(t, engine, HealthzPath)
+	if code != http.StatusServiceUnavailable || health.Checks["database"].Status != healthStatusFail {
+		t.Errorf("Test Failed!, expected the check to fail, got: %v %+v", code, health)
+	}
+	if msg := health.Checks["database"].Error; msg != "" {
+		t.Errorf("Test Failed!, expected no error in the response, got: %v", msg)
+	}
+}
@@ -2,13 +2,17 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"net"
+	"mime"
 	"net/http"
+	"net/http/cookiejar"
 	"net/url"
 	"strings"
+	"sync"
 )
 
 // HTTP util constants
@@ -69,16 +73,26 @@ func (cr CreateRequestError) Error() string {
 	return fmt.Sprintf(createRequestErrMsg, cr.Err)
 }
 
-// MakeRequestError represents an error when
+// MakeRequestError represents an error when the request cannot be made
+// Curl is only set when the debug log level is enabled and contains the curl command to reproduce the request
 type MakeRequestError struct {
-	Err error
+	Err  error
+	Curl string
 }
 
 // Error returns teh formatted MakeRequestError
 func (mr MakeRequestError) Error() string {
+	if mr.Curl != "" {
+		return fmt.Sprintf(makeRequestErrMsg, mr.Err) + " : " + fmt.Sprintf(curlMsg, mr.Curl)
+	}
 	return fmt.Sprintf(makeRequestErrMsg, mr.Err)
 }
 
+// Unwrap returns the underlying error of the MakeRequestError
+func (mr MakeRequestError) Unwrap() error {
+	return mr.Err
+}
+
 // ReadResponseError represents an error when the response cannot be read
 type ReadResponseError struct {
 	Err error
@@ -90,30 +104,176 @@ func (rr ReadResponseError) Error() string {
 }
 
 // Auth represents authentication information
+// Token is a static bearer token and TokenSource provides bearer tokens which are refreshed when they expire,
+// for example the OAuth2 ClientCredentials
+// Scheme selects how the Username and Password are sent, Basic or Digest, and defaults to Basic
 type Auth struct {
-	Username string
-	Password string
+	Username    string
+	Password    string
+	Scheme      string
+	Token       string
+	TokenSource TokenSource
+}
+
+// validate validates the authentication scheme
+func (a *Auth) validate() error {
+	switch a.Scheme {
+	case "", BasicAuthScheme, DigestAuthScheme:
+		return nil
+	}
+	return InvalidAuthSchemeError(a.Scheme)
+}
+
+// identity returns a keyed hash which identifies the credentials or an empty string if there are no credentials
+func (a *Auth) identity() string {
+	switch {
+	case a.TokenSource != nil:
+		return credentialsHash(fmt.Sprintf("%T %p", a.TokenSource, a.TokenSource))
+	case a.Token != "":
+		return credentialsHash(a.Token)
+	case a.Username != "" || a.Password != "":
+		return credentialsHash(a.Scheme, a.Username, a.Password)
+	}
+	return ""
+}
+
+// isDigest returns true if the Username and Password are used for digest authentication
+func (a *Auth) isDigest() bool {
+	return a.Scheme == DigestAuthScheme && a.TokenSource == nil && a.Token == "" && a.Username != "" && a.Password != ""
 }
 
 // RequestBody body represents the format of a request body
+// Only one of Json, Text, Form, Multipart or Reader is used, in that order of precedence
+// ContentType overrides the content type which is set based on the type of the body
 type RequestBody struct {
-	Json []byte
-	Text string
+	Json        []byte
+	Text        string
+	Form        url.Values
+	Multipart   *MultipartBody
+	Reader      io.Reader
+	ContentType string
 }
 
 // Result represents the result of a http request
+// Stream is only set for streamed requests, in which case Body is empty and the caller must close the Stream
+// ContentEncoding is the content encoding of the response body which was decoded and DecodedSize is the size
+// of the decoded body, DecodedSize is -1 for streamed requests
 type Result struct {
-	Body   []byte
-	Status string
+	Body            []byte
+	Stream          io.ReadCloser
+	Status          string
+	StatusCode      int
+	Header          http.Header
+	ContentEncoding string
+	DecodedSize     int64
+}
+
+// IsSuccess checks if the status code of the result is a 2xx status code
+func (res *Result) IsSuccess() bool {
+	return res.StatusCode >= http.StatusOK && res.StatusCode < http.StatusMultipleChoices
+}
+
+// StatusError returns a HTTPStatusError if the status code of the result is not a 2xx status code
+// The method returns nil if the request was successful
+func (res *Result) StatusError() error {
+	if res.IsSuccess() {
+		return nil
+	}
+	return HTTPStatusError{StatusCode: res.StatusCode, Body: res.Body}
+}
+
+// Client represents the settings shared by all the requests made through it
+// RateLimiter is optional and limits the rate of the outbound requests per host
+// Jar is optional and stores the cookies received from and sent to the remote servers
+// Middlewares are applied to every request made through the client, see Use
+// RedactionPolicy is optional and overrides the DefaultRedactionPolicy used for debug logging
+// Request bodies larger than CompressionThreshold bytes are compressed with gzip, zero disables the compression
+// Metrics is optional and overrides the DefaultRegistry in which the outbound requests are recorded,
+// the metrics are registered by the first request made through the client
+// The digest challenges of the remote servers are kept per client so that the nonce can be reused
+type Client struct {
+	RateLimiter          *RateLimiter
+	Jar                  http.CookieJar
+	Middlewares          []Middleware
+	RedactionPolicy      *RedactionPolicy
+	CompressionThreshold int
+	Metrics              *Registry
+
+	metrics     Middleware
+	metricsOnce sync.Once
+
+	digestSessions *digestSessionStore
+	digestOnce     sync.Once
+}
+
+// getMetrics returns the metrics Registry of the client or the DefaultRegistry
+func (c *Client) getMetrics() *Registry {
+	if c.Metrics != nil {
+		return c.Metrics
+	}
+	return DefaultRegistry
+}
+
+// metricsMiddleware returns the metrics middleware of the client, the metrics are only registered once
+// If the metrics cannot be registered the error is logged and the requests are made without recording them
+func (c *Client) metricsMiddleware() Middleware {
+	c.metricsOnce.Do(func() {
+		metrics, err := ClientMetricsMiddleware(c.getMetrics())
+		if err != nil {
+			log := LogFormatter{Msg: clientMetricsErrMsg, ErrMsg: err}
+			log.Error().Println(log.Out)
+			metrics = func(next http.RoundTripper) http.RoundTripper {
+				return next
+			}
+		}
+		c.metrics = metrics
+	})
+	return c.metrics
+}
+
+// getDigestSessions returns the digest sessions of the client, the store is created by the first request
+func (c *Client) getDigestSessions() *digestSessionStore {
+	c.digestOnce.Do(func() {
+		c.digestSessions = newDigestSessionStore(defaultMaxDigestSessions)
+	})
+	return c.digestSessions
+}
+
+// getRedactionPolicy returns the RedactionPolicy of the client or the DefaultRedactionPolicy
+func (c *Client) getRedactionPolicy() RedactionPolicy {
+	if c.RedactionPolicy != nil {
+		return *c.RedactionPolicy
+	}
+	return DefaultRedactionPolicy
 }
 
+// EnableCookieJar sets a new in memory cookie jar on the client
+func (c *Client) EnableCookieJar() {
+	// cookiejar.New never returns an error without options
+	c.Jar, _ = cookiejar.New(nil)
+}
+
+// DefaultClient is the Client used by requests which do not set a Client
+var DefaultClient = &Client{}
+
 // Request represents an HTTP request
+// Headers, Query and Cookies are added to the request created by NewRequest, Headers override the
+// default headers set based on the body and Query values are added to the query parameters of the Url
+// Ctx is optional and is used to cancel the request and any wait on the client rate limiter,
+// the request id carried by Ctx is forwarded in the X-Request-ID header
+// If Stream is set the response body is not read into memory but handed back as Result.Stream
 type Request struct {
 	Url     string
 	Method  string
 	Auth    Auth
 	Body    RequestBody
+	Headers map[string]string
+	Query   map[string]string
+	Cookies []*http.Cookie
 	Cnf     HTTPCnf
+	Ctx     context.Context
+	Client  *Client
+	Stream  bool
 	Request *http.Request
 	Result  Result
 }
@@ -134,31 +294,24 @@ type ErrResponse struct {
 	Error string `json:"error"`
 }
 
-// GetRequesterIP gets the requester IP from the request headers and returns the IP
-func GetRequesterIP(r *http.Request) string {
-	ip := r.Header.Get("X-Real-Ip")
-	if ip == "" {
-		ip = r.Header.Get("X-Forwarded-For")
-	}
-	if ip == "" {
-		ip, _, _ = net.SplitHostPort(r.RemoteAddr)
-	}
-	return ip
-}
-
 // StatusText returns a text for the HTTP status code. It returns the empty
 // string if the code is unknown.
 func StatusString(code int) string {
 	return fmt.Sprintf("%d %s", code, http.StatusText(code))
 }
 
-// NewRequest creates a base http request based on the URL method and credentials provided in the Request struct
+// NewRequest creates a base http request based on the URL method and body provided in the Request struct
+// The credentials are added to the request by the AuthMiddleware when the request is made
 // The method write the created request back into the Request struct
-// The method returns an error if the request creation fails
+// The method returns an error if the authentication scheme is not valid or the request creation fails
 func (r *Request) NewRequest() error {
 
 	var err error
 
+	if err = r.Auth.validate(); err != nil {
+		return err
+	}
+
 	if r.Cnf.SkipTLS {
 		http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
 	}
@@ -184,6 +337,54 @@ func (r *Request) NewRequest() error {
 
 		r.Request.Header.Set(contentTypeKey, textPlainContentType)
 
+	} else if r.Body.Form != nil {
+
+		r.Request, err = http.NewRequest(r.Method, r.Url, strings.NewReader(r.Body.Form.Encode()))
+
+		if err != nil {
+			return CreateRequestError{Err: err}
+		}
+
+		r.Request.Header.Set(contentTypeKey, formContentType)
+
+	} else if r.Body.Multipart != nil {
+
+		if err = r.Body.Multipart.Validate(); err != nil {
+			return err
+		}
+
+		r.Request, err = http.NewRequest(r.Method, r.Url, nil)
+
+		if err != nil {
+			return CreateRequestError{Err: err}
+		}
+
+		body, contentType, err := r.Body.Multipart.Open("")
+		if err != nil {
+			return err
+		}
+
+		r.Request.Body = body
+		r.Request.Header.Set(contentTypeKey, contentType)
+
+		if r.Body.Multipart.Replayable() {
+			_, params, _ := mime.ParseMediaType(contentType)
+			r.Request.GetBody = func() (io.ReadCloser, error) {
+				body, _, err := r.Body.Multipart.Open(params["boundary"])
+				return body, err
+			}
+		}
+
+	} else if r.Body.Reader != nil {
+
+		r.Request, err = http.NewRequest(r.Method, r.Url, r.Body.Reader)
+
+		if err != nil {
+			return CreateRequestError{Err: err}
+		}
+
+		r.Request.Header.Set(contentTypeKey, octetStreamContentType)
+
 	} else {
 
 		r.Request, err = http.NewRequest(r.Method, r.Url, nil)
@@ -194,74 +395,139 @@ func (r *Request) NewRequest() error {
 
 	}
 
-	if r.Auth.Username != "" && r.Auth.Password != "" {
-		r.Request.SetBasicAuth(r.Auth.Username, r.Auth.Password)
+	if r.Body.ContentType != "" {
+		r.Request.Header.Set(contentTypeKey, r.Body.ContentType)
+	}
+
+	for k, v := range r.Headers {
+		r.Request.Header.Set(k, v)
+	}
+
+	if len(r.Query) > 0 {
+		q := r.Request.URL.Query()
+		for k, v := range r.Query {
+			q.Set(k, v)
+		}
+		r.Request.URL.RawQuery = q.Encode()
+	}
+
+	for _, cookie := range r.Cookies {
+		r.Request.AddCookie(cookie)
+	}
+
+	if r.Ctx != nil {
+		r.Request = r.Request.WithContext(r.Ctx)
+		if id := RequestIDFromContext(r.Ctx); id != "" && r.Request.Header.Get(RequestIDHeader) == "" {
+			r.Request.Header.Set(RequestIDHeader, id)
+		}
 	}
 
 	return err
 }
 
+// getClient returns the Client set in the request or the DefaultClient
+func (r *Request) getClient() *Client {
+	if r.Client != nil {
+		return r.Client
+	}
+	return DefaultClient
+}
+
+// transport returns the base transport used for making the request
+// The method returns an error if the proxy configuration is not valid
+func (r *Request) transport() (http.RoundTripper, error) {
+	if !r.Cnf.ProxyEnable {
+		return http.DefaultTransport, nil
+	}
+
+	if err := r.Cnf.Validate(); err != nil {
+		return nil, err
+	}
+
+	proxyUrl, err := url.Parse(r.Cnf.GetProxyUrl())
+	if err != nil {
+		return nil, ProxyUrlParseError{Err: err}
+	}
+
+	log := LogFormatter{Msg: fmt.Sprintf(proxyUsedMsg, proxyUrl)}
+	log.Debug().Println(log.Out)
+
+	return &http.Transport{
+		Proxy: http.ProxyURL(proxyUrl),
+	}, nil
+}
+
 // HttpRequest makes an http request to a remote server
+// The request is passed through the middlewares of the client followed by the built-in
+// authentication, debug logging and compression middlewares
 // The response body and the status of the http response is registered into the request struct
+// For streamed requests the response body is registered as a reader which must be closed by the caller
 // The method returns an error if there is a problem with making the request or while
 // reading the response from the remote server
 func (r *Request) HttpRequest() error {
 
-	client := &http.Client{}
-
-	if r.Cnf.ProxyEnable == true {
-		if err := r.Cnf.Validate(); err != nil {
-			return err
-		}
+	c := r.getClient()
 
-		proxyUrl, err := url.Parse(r.Cnf.GetProxyUrl())
-		if err != nil {
-			return ProxyUrlParseError{Err: err}
-		}
-
-		log := LogFormatter{Msg: fmt.Sprintf(proxyUsedMsg, proxyUrl)}
-		log.Debug().Println(log.Out)
+	transport, err := r.transport()
+	if err != nil {
+		return err
+	}
 
-		transport := &http.Transport{
-			Proxy: http.ProxyURL(proxyUrl),
-		}
+	middlewares := append([]Middleware{}, c.Middlewares...)
+	middlewares = append(middlewares,
+		authMiddleware(r.Auth, c.getDigestSessions()),
+		DebugLogMiddleware(c.getRedactionPolicy()),
+		CompressionMiddleware(c.CompressionThreshold),
+		c.metricsMiddleware(),
+	)
+
+	client := &http.Client{
+		Transport: Chain(transport, middlewares...),
+		Jar:       c.Jar,
+	}
 
-		client.Transport = transport
+	host := r.Request.URL.Host
+	if err := c.RateLimiter.Wait(r.Request.Context(), host); err != nil {
+		return err
 	}
 
-	authorisation := r.Request.Header.Get("Authorization")
-	r.Request.Header.Set("Authorization", "")
-	log := LogFormatter{Msg: fmt.Sprintf("Request : %v", r.Request)}
-	log.Debug().Println(log.Out)
-	log = LogFormatter{Msg: fmt.Sprintf("Request Url : %v", r.Request.URL)}
-	log.Debug().Println(log.Out)
-	log = LogFormatter{Msg: fmt.Sprintf("Request Headers : %v", r.Request.Header)}
-	log.Debug().Println(log.Out)
-	log = LogFormatter{Msg: fmt.Sprintf("Request Body : %v", r.Request.Body)}
-	log.Debug().Println(log.Out)
-	r.Request.Header.Set("Authorization", authorisation)
+	req := r.Request
+	if id := r.Auth.identity(); id != "" {
+		// the credentials are only added by the AuthMiddleware, the identity lets the middlewares
+		// registered on the client tell requests made with different credentials apart
+		req = req.WithContext(context.WithValue(req.Context(), credentialsContextKey{}, id))
+	}
 
-	resp, err := client.Do(r.Request)
+	resp, err := client.Do(req)
 	if err != nil {
+		if LogLevel == debugLogLevel {
+			return MakeRequestError{Err: err, Curl: r.Curl()}
+		}
 		return MakeRequestError{Err: err}
 	}
 
+	c.RateLimiter.Update(host, resp)
+
+	r.Result.Status = resp.Status
+	r.Result.StatusCode = resp.StatusCode
+	r.Result.ContentEncoding = resp.Header.Get(decodedContentEncodingKey)
+	resp.Header.Del(decodedContentEncodingKey)
+	r.Result.Header = resp.Header.Clone()
+	r.Result.Body = nil
+	r.Result.Stream = nil
+
+	if r.Stream {
+		r.Result.Stream = resp.Body
+		r.Result.DecodedSize = -1
+		return nil
+	}
+
 	r.Result.Body, err = ioutil.ReadAll(resp.Body)
 	if err != nil {
+		resp.Body.Close()
 		return ReadResponseError{Err: err}
 	}
-
-	r.Result.Status = resp.Status
-
-	resp.Header.Set("Authorization", "")
-	log = LogFormatter{Msg: fmt.Sprintf("Response : %v", resp)}
-	log.Debug().Println(log.Out)
-	log = LogFormatter{Msg: fmt.Sprintf("Response Headers : %v", resp.Header)}
-	log.Debug().Println(log.Out)
-	log = LogFormatter{Msg: fmt.Sprintf("Response Status : %v", resp.Status)}
-	log.Debug().Println(log.Out)
-	log = LogFormatter{Msg: fmt.Sprintf("Response Body : %v", string(r.Result.Body))}
-	log.Debug().Println(log.Out)
+	r.Result.DecodedSize = int64(len(r.Result.Body))
 
 	err = resp.Body.Close()
 	if err != nil {
@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Instrumentation util constants
+const (
+	MetricsPath = "/metrics"
+
+	serverRequestsMetric        = "http_server_requests_total"
+	serverRequestsHelp          = "Total number of HTTP requests received by the server"
+	serverRequestDurationMetric = "http_server_request_duration_seconds"
+	serverRequestDurationHelp   = "Latency of the HTTP requests received by the server"
+	clientRequestsMetric        = "http_client_requests_total"
+	clientRequestsHelp          = "Total number of outbound HTTP requests made by the client"
+	clientRequestDurationMetric = "http_client_request_duration_seconds"
+	clientRequestDurationHelp   = "Latency of the outbound HTTP requests until the response headers are received"
+
+	routeLabel       = "route"
+	methodLabel      = "method"
+	statusLabel      = "status"
+	hostLabel        = "host"
+	unmatchedRoute   = "unmatched"
+	errorStatusLabel = "error"
+
+	clientMetricsErrMsg = "Unable to register the client metrics, the outbound requests are not recorded"
+)
+
+// MetricsCnf represents the configuration of the metrics endpoint of the server
+// The endpoint is served on Path, which defaults to /metrics, when it is enabled
+// The endpoint is not authenticated, to protect it leave it disabled and register the MetricsHandler
+// on a route group with authentication instead
+type MetricsCnf struct {
+	Enable bool   `yaml:"enable" mapstructure:"enable"`
+	Path   string `yaml:"path" mapstructure:"path"`
+}
+
+// path returns the path of the metrics endpoint
+func (mc *MetricsCnf) path() string {
+	if mc.Path == "" {
+		return MetricsPath
+	}
+	return mc.Path
+}
+
+// MetricsHandler returns a gin handler which writes the metrics of the registry in the text exposition format
+func MetricsHandler(registry *Registry) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Header(contentTypeKey, textExpositionContentType)
+		ctx.Status(http.StatusOK)
+		_, _ = registry.WriteTo(ctx.Writer)
+	}
+}
+
+// RequestMetrics returns a gin middleware which records the number and latency of the requests
+// by route, method and status code
+// Requests without a matching route are recorded with the route "unmatched"
+// The method returns an error if the metrics cannot be registered
+func RequestMetrics(registry *Registry) (gin.HandlerFunc, error) {
+	requests, err := registry.NewCounter(serverRequestsMetric, serverRequestsHelp, routeLabel, methodLabel, statusLabel)
+	if err != nil {
+		return nil, err
+	}
+	duration, err := registry.NewHistogram(serverRequestDurationMetric, serverRequestDurationHelp, nil, routeLabel, methodLabel, statusLabel)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(ctx.Writer.Status())
+
+		requests.Inc(route, ctx.Request.Method, status)
+		duration.Observe(time.Since(start).Seconds(), route, ctx.Request.Method, status)
+	}, nil
+}
+
+// ClientMetricsMiddleware returns a middleware which records the number and latency of the outbound
+// requests by host, method and status code, requests which fail without a response have the status "error"
+// The method returns an error if the metrics cannot be registered
+func ClientMetricsMiddleware(registry *Registry) (Middleware, error) {
+	requests, err := registry.NewCounter(clientRequestsMetric, clientRequestsHelp, hostLabel, methodLabel, statusLabel)
+	if err != nil {
+		return nil, err
+	}
+	duration, err := registry.NewHistogram(clientRequestDurationMetric, clientRequestDurationHelp, nil, hostLabel, methodLabel, statusLabel)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+
+			status := errorStatusLabel
+			if err == nil {
+				status = strconv.Itoa(resp.StatusCode)
+			}
+			requests.Inc(req.URL.Host, req.Method, status)
+			duration.Observe(time.Since(start).Seconds(), req.URL.Host, req.Method, status)
+
+			return resp, err
+		})
+	}, nil
+}
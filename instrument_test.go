@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestMetrics(t *testing.T) {
+	registry := NewRegistry()
+	metrics, err := RequestMetrics(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	engine := gin.New()
+	engine.Use(metrics)
+	engine.GET("/users/:id", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "ok")
+	})
+	engine.GET(MetricsPath, MetricsHandler(registry))
+
+	for _, path := range []string{"/users/1", "/users/2", "/unknown"} {
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, MetricsPath, nil))
+	if w.Header().Get(contentTypeKey) != textExpositionContentType {
+		t.Errorf("Test Failed!, expected: %v, got: %v", textExpositionContentType, w.Header().Get(contentTypeKey))
+	}
+	for _, expected := range []string{
+		`http_server_requests_total{route="/users/:id",method="GET",status="200"} 2`,
+		`http_server_requests_total{route="unmatched",method="GET",status="404"} 1`,
+		`http_server_request_duration_seconds_count{route="/users/:id",method="GET",status="200"} 2`,
+	} {
+		if !strings.Contains(w.Body.String(), expected) {
+			t.Errorf("Test Failed!, expected %q in:\n%s", expected, w.Body.String())
+		}
+	}
+}
+
+func TestClientMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	registry := NewRegistry()
+	r := Request{Url: server.URL, Method: http.MethodPost, Client: &Client{Metrics: registry}}
+	if err := r.NewRequest(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.HttpRequest(); err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	if _, err := registry.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(server.URL)
+	expected := `http_client_requests_total{host="` + u.Host + `",method="POST",status="202"} 1`
+	if !strings.Contains(b.String(), expected) {
+		t.Errorf("Test Failed!, expected %q in:\n%s", expected, b.String())
+	}
+}
+
+func TestClientMetricsConflict(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	registry := NewRegistry()
+	if _, err := registry.NewGauge(clientRequestsMetric, "conflicting metric"); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &Client{Metrics: registry}
+	for i := 0; i < 2; i++ {
+		r := Request{Url: server.URL, Method: http.MethodGet, Client: client}
+		if err := r.NewRequest(); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.HttpRequest(); err != nil {
+			t.Errorf("Test Failed!, expected the request to succeed without metrics, got: %v", err)
+		}
+	}
+}
+
+func TestServerMetricsEndpoint(t *testing.T) {
+	for _, cnf := range []MetricsCnf{{}, {Enable: true}, {Enable: true, Path: "/internal/metrics"}} {
+		s, err := NewServer(ServerCnf{Host: "127.0.0.1", Port: "0", LoggerCnf: LoggerCnf{Level: infoLogLevel}, MetricsCnf: cnf})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		s.Engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, cnf.path(), nil))
+		expected := http.StatusNotFound
+		if cnf.Enable {
+			expected = http.StatusOK
+		}
+		if w.Code != expected {
+			t.Errorf("Test Failed!, %+v expected: %v, got: %v", cnf, expected, w.Code)
+		}
+	}
+}
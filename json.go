@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// JSON request util constants
+const (
+	httpStatusErrMsg      = "Unexpected response status '%s' : %s"
+	httpStatusErrBodySize = 512
+)
+
+// HTTPStatusError represents an error when the remote server responds with a non 2xx status code
+type HTTPStatusError struct {
+	StatusCode int
+	Body       []byte
+}
+
+// Error returns the formatted HTTPStatusError
+// The response body is truncated to keep the error message readable
+func (hs HTTPStatusError) Error() string {
+	body := hs.Body
+	if len(body) > httpStatusErrBodySize {
+		body = body[:httpStatusErrBodySize]
+	}
+	return fmt.Sprintf(httpStatusErrMsg, StatusString(hs.StatusCode), string(body))
+}
+
+// getHTTPCnf returns the http configuration set globally through HTTPCnf.Set
+func getHTTPCnf() HTTPCnf {
+	return HTTPCnf{
+		SkipTLS:       SkipTLS,
+		ProxyEnable:   ProxyEnabled,
+		ProxyProtocol: ProxyProtocol,
+		ProxyHost:     ProxyHost,
+		ProxyPort:     ProxyPort,
+	}
+}
+
+// DoJSON encodes in as the JSON body of the request, makes the request and decodes the JSON response into out
+// in and out are optional, out should be a pointer to a valid struct
+// The method returns an error if the encoding, the request or the decoding fails
+// and a HTTPStatusError if the remote server responds with a non 2xx status code
+func (r *Request) DoJSON(in, out interface{}) error {
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return JSONMarshalError{Err: err}
+		}
+		r.Body.Json = data
+	}
+
+	if err := r.NewRequest(); err != nil {
+		return err
+	}
+	r.Request.Header.Set(acceptKey, applicationJsonContentType)
+
+	if err := r.HttpRequest(); err != nil {
+		return err
+	}
+
+	if err := r.Result.StatusError(); err != nil {
+		return err
+	}
+
+	if out != nil && len(r.Result.Body) > 0 {
+		if err := json.Unmarshal(r.Result.Body, out); err != nil {
+			return JSONUnMarshalError{Err: err}
+		}
+	}
+
+	return nil
+}
+
+// doJSON makes a JSON request using the global http configuration
+func doJSON(method, url string, in, out interface{}) error {
+	r := Request{Url: url, Method: method, Cnf: getHTTPCnf()}
+	return r.DoJSON(in, out)
+}
+
+// GetJSON makes a GET request to the url and decodes the JSON response into out
+// The method returns a HTTPStatusError if the remote server responds with a non 2xx status code
+func GetJSON(url string, out interface{}) error {
+	return doJSON(http.MethodGet, url, nil, out)
+}
+
+// PostJSON makes a POST request to the url with in as the JSON body and decodes the JSON response into out
+// The method returns a HTTPStatusError if the remote server responds with a non 2xx status code
+func PostJSON(url string, in, out interface{}) error {
+	return doJSON(http.MethodPost, url, in, out)
+}
+
+// PutJSON makes a PUT request to the url with in as the JSON body and decodes the JSON response into out
+// The method returns a HTTPStatusError if the remote server responds with a non 2xx status code
+func PutJSON(url string, in, out interface{}) error {
+	return doJSON(http.MethodPut, url, in, out)
+}
+
+// PatchJSON makes a PATCH request to the url with in as the JSON body and decodes the JSON response into out
+// The method returns a HTTPStatusError if the remote server responds with a non 2xx status code
+func PatchJSON(url string, in, out interface{}) error {
+	return doJSON(http.MethodPatch, url, in, out)
+}
+
+// DeleteJSON makes a DELETE request to the url and decodes the JSON response into out
+// The method returns a HTTPStatusError if the remote server responds with a non 2xx status code
+func DeleteJSON(url string, out interface{}) error {
+	return doJSON(http.MethodDelete, url, nil, out)
+}
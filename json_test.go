@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type jsonTestItem struct {
+	Name string `json:"name"`
+}
+
+func TestPostJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in jsonTestItem
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set(contentTypeKey, applicationJsonContentType)
+		_ = json.NewEncoder(w).Encode(jsonTestItem{Name: in.Name + "-created"})
+	}))
+	defer server.Close()
+
+	var out jsonTestItem
+	if err := PostJSON(server.URL, jsonTestItem{Name: "item"}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.Name != "item-created" {
+		t.Errorf("Test Failed!, expected: %v, got: %v", "item-created", out.Name)
+	}
+}
+
+func TestGetJSONStatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":"not found"}`))
+	}))
+	defer server.Close()
+
+	var out jsonTestItem
+	err := GetJSON(server.URL, &out)
+
+	hse, ok := err.(HTTPStatusError)
+	if !ok {
+		t.Fatalf("Test Failed!, expected: %v, got: %v", "HTTPStatusError", err)
+	}
+	if hse.StatusCode != http.StatusNotFound {
+		t.Errorf("Test Failed!, expected: %v, got: %v", http.StatusNotFound, hse.StatusCode)
+	}
+	if string(hse.Body) != `{"error":"not found"}` {
+		t.Errorf("Test Failed!, expected: %v, got: %v", `{"error":"not found"}`, string(hse.Body))
+	}
+}
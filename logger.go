@@ -106,6 +106,7 @@ func (l *LogFormatter) GetErrMsg() string {
 // GetLogMsg formats a message based on the values set for Request and Message set for the Logger receiver
 // If Request variable is nil only the message will be returned
 // else a formatted string will the request details along with the message will be returned
+// The request id of the request is appended to the message if it is set, see RequestID
 func (l *LogFormatter) GetLogMsg() string {
 
 	if l.Msg != "" && l.ErrMsg != nil {
@@ -127,6 +128,10 @@ func (l *LogFormatter) GetLogMsg() string {
 			methodColor, l.Request.Method, resetColor,
 			l.Request.RequestURI,
 			l.Out)
+
+		if id := RequestIDFromContext(l.Request.Context()); id != "" {
+			l.Out += " | request_id=" + id
+		}
 	}
 
 	return l.Out
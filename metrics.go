@@ -0,0 +1,336 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Metrics util constants
+const (
+	counterMetricType   = "counter"
+	gaugeMetricType     = "gauge"
+	histogramMetricType = "histogram"
+
+	invalidMetricNameErrMsg   = "Invalid metric or label name '%s'"
+	metricConflictErrMsg      = "Metric '%s' is already registered as a %s with labels %v"
+	metricLabelCountErrMsg    = "Metric '%s' expects %d label values, got %d"
+	metricRecordFailedMsg     = "Unable to record metric"
+	labelSeparator            = "\xff"
+	metricNameRegex           = `^[a-zA-Z_:][a-zA-Z0-9_:]*$`
+	textExpositionContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+var (
+	// DefaultBuckets are the default histogram buckets in seconds
+	DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	// DefaultRegistry is the registry used by the server and the http client
+	DefaultRegistry = NewRegistry()
+
+	metricNamePattern = regexp.MustCompile(metricNameRegex)
+)
+
+// InvalidMetricNameError represents an error when a metric or label name is not valid
+type InvalidMetricNameError string
+
+// Error returns the formatted InvalidMetricNameError
+func (imn InvalidMetricNameError) Error() string {
+	return fmt.Sprintf(invalidMetricNameErrMsg, string(imn))
+}
+
+// MetricConflictError represents an error when a metric is registered again with a different type or labels
+type MetricConflictError struct {
+	Name   string
+	Type   string
+	Labels []string
+}
+
+// Error returns the formatted MetricConflictError
+func (mc MetricConflictError) Error() string {
+	return fmt.Sprintf(metricConflictErrMsg, mc.Name, mc.Type, mc.Labels)
+}
+
+// MetricLabelCountError represents an error when the number of label values does not match the label names
+type MetricLabelCountError struct {
+	Name     string
+	Expected int
+	Got      int
+}
+
+// Error returns the formatted MetricLabelCountError
+func (mlc MetricLabelCountError) Error() string {
+	return fmt.Sprintf(metricLabelCountErrMsg, mlc.Name, mlc.Expected, mlc.Got)
+}
+
+// series holds the values of a metric for a set of label values
+type series struct {
+	labelValues []string
+	value       float64
+	buckets     []uint64
+	sum         float64
+	count       uint64
+}
+
+// metric represents a metric family with all its series
+type metric struct {
+	name       string
+	help       string
+	metricType string
+	labelNames []string
+	buckets    []float64
+	series     map[string]*series
+	mu         sync.Mutex
+}
+
+// get returns the series for the label values, the series is created if it does not exist
+// The method must be called with the lock held
+func (m *metric) get(labelValues []string) (*series, error) {
+	if len(labelValues) != len(m.labelNames) {
+		return nil, MetricLabelCountError{Name: m.name, Expected: len(m.labelNames), Got: len(labelValues)}
+	}
+	key := strings.Join(labelValues, labelSeparator)
+	s, ok := m.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		if m.metricType == histogramMetricType {
+			s.buckets = make([]uint64, len(m.buckets))
+		}
+		m.series[key] = s
+	}
+	return s, nil
+}
+
+// update updates the series for the label values and logs an error if the label values are not valid
+func (m *metric) update(labelValues []string, fn func(s *series)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, err := m.get(labelValues)
+	if err != nil {
+		log := LogFormatter{Msg: metricRecordFailedMsg, ErrMsg: err}
+		log.Error().Println(log.Out)
+		return
+	}
+	fn(s)
+}
+
+// Counter is a metric which only goes up
+type Counter struct {
+	m *metric
+}
+
+// Inc increments the counter by one
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds the value to the counter, negative values are ignored
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	c.m.update(labelValues, func(s *series) {
+		s.value += v
+	})
+}
+
+// Gauge is a metric which can go up and down
+type Gauge struct {
+	m *metric
+}
+
+// Set sets the gauge to the value
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.m.update(labelValues, func(s *series) {
+		s.value = v
+	})
+}
+
+// Add adds the value to the gauge, the value can be negative
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.m.update(labelValues, func(s *series) {
+		s.value += v
+	})
+}
+
+// Inc increments the gauge by one
+func (g *Gauge) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+// Dec decrements the gauge by one
+func (g *Gauge) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+// Histogram is a metric which counts the observed values in buckets
+type Histogram struct {
+	m *metric
+}
+
+// Observe adds the value to the histogram
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.m.update(labelValues, func(s *series) {
+		for i, upper := range h.m.buckets {
+			if v <= upper {
+				s.buckets[i]++
+			}
+		}
+		s.sum += v
+		s.count++
+	})
+}
+
+// Registry holds the metrics and writes them in the Prometheus text exposition format
+type Registry struct {
+	metrics map[string]*metric
+	mu      sync.Mutex
+}
+
+// NewRegistry returns a new empty Registry
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]*metric)}
+}
+
+// register registers a new metric or returns the metric which is already registered with the same name
+// The method returns an error if the names are not valid or the metric is already registered with a
+// different type or different labels
+func (r *Registry) register(name, help, metricType string, buckets []float64, labelNames []string) (*metric, error) {
+	for _, n := range append([]string{name}, labelNames...) {
+		if !metricNamePattern.MatchString(n) {
+			return nil, InvalidMetricNameError(n)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if m, ok := r.metrics[name]; ok {
+		if m.metricType != metricType || strings.Join(m.labelNames, ",") != strings.Join(labelNames, ",") {
+			return nil, MetricConflictError{Name: name, Type: m.metricType, Labels: m.labelNames}
+		}
+		return m, nil
+	}
+
+	m := &metric{
+		name:       name,
+		help:       help,
+		metricType: metricType,
+		labelNames: labelNames,
+		buckets:    buckets,
+		series:     make(map[string]*series),
+	}
+	r.metrics[name] = m
+	return m, nil
+}
+
+// NewCounter registers a counter with the label names
+// The method returns the registered counter if a counter with the same name and labels already exists
+func (r *Registry) NewCounter(name, help string, labelNames ...string) (*Counter, error) {
+	m, err := r.register(name, help, counterMetricType, nil, labelNames)
+	if err != nil {
+		return nil, err
+	}
+	return &Counter{m: m}, nil
+}
+
+// NewGauge registers a gauge with the label names
+// The method returns the registered gauge if a gauge with the same name and labels already exists
+func (r *Registry) NewGauge(name, help string, labelNames ...string) (*Gauge, error) {
+	m, err := r.register(name, help, gaugeMetricType, nil, labelNames)
+	if err != nil {
+		return nil, err
+	}
+	return &Gauge{m: m}, nil
+}
+
+// NewHistogram registers a histogram with the buckets and label names, DefaultBuckets are used if buckets is empty
+// The method returns the registered histogram if a histogram with the same name and labels already exists
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labelNames ...string) (*Histogram, error) {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	m, err := r.register(name, help, histogramMetricType, buckets, labelNames)
+	if err != nil {
+		return nil, err
+	}
+	return &Histogram{m: m}, nil
+}
+
+// formatFloat formats a value of the text exposition format
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// formatLabels formats the label names and values of a series
+func formatLabels(names, values []string, extra ...string) string {
+	var pairs []string
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	for i, name := range names {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, escaper.Replace(values[i])))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, extra[i], extra[i+1]))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// WriteTo writes all the metrics in the Prometheus text exposition format sorted by name and label values
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	metrics := make([]*metric, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		metrics = append(metrics, m)
+	}
+	r.mu.Unlock()
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].name < metrics[j].name
+	})
+
+	var b strings.Builder
+	for _, m := range metrics {
+		name := m.name
+		m.mu.Lock()
+		keys := make([]string, 0, len(m.series))
+		for key := range m.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		fmt.Fprintf(&b, "# HELP %s %s\n", name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(m.help))
+		fmt.Fprintf(&b, "# TYPE %s %s\n", name, m.metricType)
+		for _, key := range keys {
+			s := m.series[key]
+			if m.metricType != histogramMetricType {
+				fmt.Fprintf(&b, "%s%s %s\n", name, formatLabels(m.labelNames, s.labelValues), formatFloat(s.value))
+				continue
+			}
+			for i, upper := range m.buckets {
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", name, formatLabels(m.labelNames, s.labelValues, "le", formatFloat(upper)), s.buckets[i])
+			}
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", name, formatLabels(m.labelNames, s.labelValues, "le", "+Inf"), s.count)
+			fmt.Fprintf(&b, "%s_sum%s %s\n", name, formatLabels(m.labelNames, s.labelValues), formatFloat(s.sum))
+			fmt.Fprintf(&b, "%s_count%s %d\n", name, formatLabels(m.labelNames, s.labelValues), s.count)
+		}
+		m.mu.Unlock()
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
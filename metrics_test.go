@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+
+	c, err := r.NewCounter("jobs_total", "Total jobs", "queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Inc("default")
+	c.Add(2, "default")
+	c.Add(-1, "default")
+	c.Inc(`a"b`)
+
+	g, err := r.NewGauge("workers", "Active workers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Set(5)
+	g.Dec()
+
+	h, err := r.NewHistogram("duration_seconds", "Job duration", []float64{1, 0.1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(2)
+
+	var b strings.Builder
+	if _, err := r.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# HELP duration_seconds Job duration
+# TYPE duration_seconds histogram
+duration_seconds_bucket{le="0.1"} 1
+duration_seconds_bucket{le="1"} 2
+duration_seconds_bucket{le="+Inf"} 3
+duration_seconds_sum 2.55
+duration_seconds_count 3
+# HELP jobs_total Total jobs
+# TYPE jobs_total counter
+jobs_total{queue="a\"b"} 1
+jobs_total{queue="default"} 3
+# HELP workers Active workers
+# TYPE workers gauge
+workers 4
+`
+	if b.String() != expected {
+		t.Errorf("Test Failed!, expected:\n%s\ngot:\n%s", expected, b.String())
+	}
+}
+
+func TestRegistryErrors(t *testing.T) {
+	r := NewRegistry()
+
+	if _, err := r.NewCounter("invalid-name", "help"); err == nil {
+		t.Error("Test Failed!, expected an InvalidMetricNameError")
+	}
+
+	c1, err := r.NewCounter("requests_total", "help", "code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := r.NewCounter("requests_total", "help", "code")
+	if err != nil || c1.m != c2.m {
+		t.Errorf("Test Failed!, expected the registered counter to be returned, got: %v", err)
+	}
+	if _, err := r.NewGauge("requests_total", "help", "code"); err == nil {
+		t.Error("Test Failed!, expected a MetricConflictError")
+	} else if _, ok := err.(MetricConflictError); !ok {
+		t.Errorf("Test Failed!, expected: MetricConflictError, got: %T", err)
+	}
+
+	// label values which do not match the label names are not recorded
+	c1.Inc()
+	if len(c1.m.series) != 0 {
+		t.Errorf("Test Failed!, expected no series, got: %v", len(c1.m.series))
+	}
+}
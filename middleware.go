@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Middleware util constants
+const (
+	authRetryMsg = "Received %s, retrying the request once with a new token"
+)
+
+// RoundTripperFunc is an adapter to allow the use of ordinary functions as a http.RoundTripper
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req)
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps a http.RoundTripper to see and modify the outbound requests and their responses
+// A middleware must not modify the request it receives, it should modify a clone of the request instead
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// Chain wraps the round tripper with the middlewares
+// The first middleware is the outermost middleware and sees the request first and the response last
+func Chain(rt http.RoundTripper, middlewares ...Middleware) http.RoundTripper {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		rt = middlewares[i](rt)
+	}
+	return rt
+}
+
+// Use registers the middlewares on the client
+// The middlewares are called in the order in which they are registered before the built-in
+// authentication and debug logging middlewares
+func (c *Client) Use(middlewares ...Middleware) {
+	c.Middlewares = append(c.Middlewares, middlewares...)
+}
+
+// AuthMiddleware returns a middleware which authenticates the requests using the authentication information
+// The credentials are only sent to the host of the first request made through the middleware,
+// redirects to other hosts are followed without credentials
+// Requests using digest authentication answer the digest challenge of the remote server
+// Requests authenticated using a TokenSource are retried once with a new token if the
+// remote server responds with 401 Unauthorized and the request body can be replayed
+func AuthMiddleware(auth Auth) Middleware {
+	return authMiddleware(auth, newDigestSessionStore(defaultMaxDigestSessions))
+}
+
+// authMiddleware returns the AuthMiddleware which keeps the digest challenges in the sessions
+func authMiddleware(auth Auth, sessions *digestSessionStore) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		var host string
+		var once sync.Once
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			once.Do(func() {
+				host = req.URL.Host
+			})
+			if !strings.EqualFold(req.URL.Host, host) {
+				return next.RoundTrip(req)
+			}
+
+			if auth.isDigest() {
+				return digestRoundTrip(next, req, auth, sessions)
+			}
+
+			authReq := req.Clone(req.Context())
+			if err := auth.setAuthorization(authReq); err != nil {
+				return nil, err
+			}
+
+			resp, err := next.RoundTrip(authReq)
+			if err != nil || resp.StatusCode != http.StatusUnauthorized || auth.TokenSource == nil {
+				return resp, err
+			}
+			if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+				return resp, nil
+			}
+
+			log := LogFormatter{Msg: fmt.Sprintf(authRetryMsg, resp.Status)}
+			log.Debug().Println(log.Out)
+
+			resp.Body.Close()
+			auth.TokenSource.Invalidate()
+
+			retryReq := req.Clone(req.Context())
+			if err := auth.setAuthorization(retryReq); err != nil {
+				return nil, err
+			}
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				retryReq.Body = body
+			}
+
+			return next.RoundTrip(retryReq)
+		})
+	}
+}
+
+// DebugLogMiddleware returns a middleware which logs the requests and responses at debug level
+// The headers and bodies are redacted and truncated according to the RedactionPolicy before they are logged
+func DebugLogMiddleware(policy RedactionPolicy) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if LogLevel != debugLogLevel {
+				return next.RoundTrip(req)
+			}
+
+			log := LogFormatter{Msg: fmt.Sprintf("Request : %s %v", req.Method, req.URL)}
+			log.Debug().Println(log.Out)
+			log = LogFormatter{Msg: fmt.Sprintf("Request Headers : %v", policy.RedactHeaders(req.Header))}
+			log.Debug().Println(log.Out)
+			if policy.MaxBodySize >= 0 {
+				log = LogFormatter{Msg: fmt.Sprintf("Request Body : %s", policy.peekRequestBody(req))}
+				log.Debug().Println(log.Out)
+			}
+
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				return resp, err
+			}
+
+			log = LogFormatter{Msg: fmt.Sprintf("Response Status : %v", resp.Status)}
+			log.Debug().Println(log.Out)
+			log = LogFormatter{Msg: fmt.Sprintf("Response Headers : %v", policy.RedactHeaders(resp.Header))}
+			log.Debug().Println(log.Out)
+			if policy.MaxBodySize >= 0 {
+				log = LogFormatter{Msg: fmt.Sprintf("Response Body : %s", policy.peekResponseBody(resp))}
+				log.Debug().Println(log.Out)
+			}
+
+			return resp, nil
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientMiddlewares(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Trace-Id", r.Header.Get("X-Trace-Id"))
+	}))
+	defer server.Close()
+
+	var order []string
+	trace := func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			order = append(order, "trace")
+			req = req.Clone(req.Context())
+			req.Header.Set("X-Trace-Id", "trace-id")
+			return next.RoundTrip(req)
+		})
+	}
+	record := func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			order = append(order, "record")
+			resp, err := next.RoundTrip(req)
+			if err == nil {
+				order = append(order, "record:"+resp.Header.Get("X-Trace-Id"))
+			}
+			return resp, err
+		})
+	}
+
+	client := &Client{}
+	client.Use(trace, record)
+
+	r := Request{Url: server.URL, Method: http.MethodGet, Client: client}
+	if err := r.NewRequest(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.HttpRequest(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"trace", "record", "record:trace-id"}
+	if len(order) != len(expected) {
+		t.Fatalf("Test Failed!, expected: %v, got: %v", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Errorf("Test Failed!, expected: %v, got: %v", expected, order)
+		}
+	}
+	if r.Result.Header.Get("X-Trace-Id") != "trace-id" {
+		t.Errorf("Test Failed!, expected: %v, got: %v", "trace-id", r.Result.Header.Get("X-Trace-Id"))
+	}
+}
+
+func TestAuthMiddlewareRedirect(t *testing.T) {
+	var leaked []string
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get(authorizationKey); auth != "" {
+			leaked = append(leaked, auth)
+		}
+	}))
+	defer other.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(authorizationKey) == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/local" {
+			http.Redirect(w, r, "/done", http.StatusFound)
+			return
+		}
+		if r.URL.Path == "/done" {
+			return
+		}
+		http.Redirect(w, r, other.URL, http.StatusFound)
+	}))
+	defer server.Close()
+
+	for _, auth := range []Auth{{Username: "user", Password: "pass"}, {Token: "secret"}} {
+		r := Request{Url: server.URL, Method: http.MethodGet, Auth: auth}
+		if err := r.NewRequest(); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.HttpRequest(); err != nil {
+			t.Fatal(err)
+		}
+		if r.Result.StatusCode != http.StatusOK {
+			t.Errorf("Test Failed!, expected: %v, got: %v", http.StatusOK, r.Result.StatusCode)
+		}
+
+		// redirects on the same host keep the credentials
+		r = Request{Url: server.URL + "/local", Method: http.MethodGet, Auth: auth}
+		if err := r.NewRequest(); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.HttpRequest(); err != nil {
+			t.Fatal(err)
+		}
+		if r.Result.StatusCode != http.StatusOK {
+			t.Errorf("Test Failed!, expected: %v, got: %v", http.StatusOK, r.Result.StatusCode)
+		}
+	}
+
+	if len(leaked) != 0 {
+		t.Errorf("Test Failed!, expected no credentials on the other host, got: %v", leaked)
+	}
+}
@@ -0,0 +1,201 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OAuth2 util constants
+const (
+	authorizationKey       = "Authorization"
+	bearerTokenType        = "Bearer"
+	clientCredentialsGrant = "client_credentials"
+	defaultTokenExpiry     = time.Hour
+	defaultExpiryDelta     = 30 * time.Second
+
+	tokenFetchErrMsg = "Unable to fetch OAuth2 token from '%s' : %v"
+	emptyTokenErrMsg = "the response does not contain an access token"
+	tokenFetchedMsg  = "Fetched a new OAuth2 token from '%s', the token expires at %v"
+)
+
+// TokenFetchError represents an error when an OAuth2 token cannot be fetched from the token endpoint
+type TokenFetchError struct {
+	Url string
+	Err error
+}
+
+// Error returns the formatted TokenFetchError
+func (tf TokenFetchError) Error() string {
+	return fmt.Sprintf(tokenFetchErrMsg, tf.Url, tf.Err)
+}
+
+// Token represents an OAuth2 access token
+type Token struct {
+	AccessToken string
+	TokenType   string
+	Expiry      time.Time
+}
+
+// Valid checks if the token is set and does not expire within the delta
+func (t *Token) Valid(delta time.Duration) bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || time.Now().Add(delta).Before(t.Expiry)
+}
+
+// AuthorizationHeader returns the value of the Authorization header for the token
+func (t *Token) AuthorizationHeader() string {
+	tokenType := t.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, bearerTokenType) {
+		tokenType = bearerTokenType
+	}
+	return tokenType + " " + t.AccessToken
+}
+
+// TokenSource returns the tokens used to authenticate requests
+// Invalidate is called when the remote server rejects a token, the next call to Token should return a new token
+type TokenSource interface {
+	Token(ctx context.Context) (*Token, error)
+	Invalidate()
+}
+
+// ClientCredentials is a TokenSource which fetches tokens using the OAuth2 client credentials grant
+// Tokens are cached and refreshed ExpiryDelta before they expire, ExpiryDelta defaults to 30 seconds
+// The client id and secret are sent using basic authentication unless AuthInBody is set
+type ClientCredentials struct {
+	TokenUrl     string
+	ClientId     string
+	ClientSecret string
+	Scopes       []string
+	Params       map[string]string
+	AuthInBody   bool
+	ExpiryDelta  time.Duration
+	Cnf          HTTPCnf
+	Client       *Client
+	mu           sync.Mutex
+	token        *Token
+}
+
+// tokenResponse represents the response of an OAuth2 token endpoint
+type tokenResponse struct {
+	AccessToken string      `json:"access_token"`
+	TokenType   string      `json:"token_type"`
+	ExpiresIn   json.Number `json:"expires_in"`
+}
+
+// Token returns the cached token or fetches a new token if the cached token is about to expire
+// The method returns an error if the token cannot be fetched
+func (cc *ClientCredentials) Token(ctx context.Context) (*Token, error) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	delta := cc.ExpiryDelta
+	if delta == 0 {
+		delta = defaultExpiryDelta
+	}
+
+	if cc.token.Valid(delta) {
+		return cc.token, nil
+	}
+
+	token, err := cc.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cc.token = token
+
+	return token, nil
+}
+
+// Invalidate removes the cached token
+func (cc *ClientCredentials) Invalidate() {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.token = nil
+}
+
+// fetch requests a new token from the token endpoint
+func (cc *ClientCredentials) fetch(ctx context.Context) (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", clientCredentialsGrant)
+	if len(cc.Scopes) > 0 {
+		form.Set("scope", strings.Join(cc.Scopes, " "))
+	}
+	for k, v := range cc.Params {
+		form.Set(k, v)
+	}
+
+	r := Request{
+		Url:     cc.TokenUrl,
+		Method:  http.MethodPost,
+		Body:    RequestBody{Form: form},
+		Headers: map[string]string{acceptKey: applicationJsonContentType},
+		Cnf:     cc.Cnf,
+		Ctx:     ctx,
+		Client:  cc.Client,
+	}
+	if cc.AuthInBody {
+		form.Set("client_id", cc.ClientId)
+		form.Set("client_secret", cc.ClientSecret)
+	} else {
+		r.Auth = Auth{Username: url.QueryEscape(cc.ClientId), Password: url.QueryEscape(cc.ClientSecret)}
+	}
+
+	if err := r.NewRequest(); err != nil {
+		return nil, TokenFetchError{Url: cc.TokenUrl, Err: err}
+	}
+	if err := r.HttpRequest(); err != nil {
+		return nil, TokenFetchError{Url: cc.TokenUrl, Err: err}
+	}
+	if err := r.Result.StatusError(); err != nil {
+		return nil, TokenFetchError{Url: cc.TokenUrl, Err: err}
+	}
+
+	var tr tokenResponse
+	if err := json.Unmarshal(r.Result.Body, &tr); err != nil {
+		return nil, TokenFetchError{Url: cc.TokenUrl, Err: JSONUnMarshalError{Err: err}}
+	}
+	if tr.AccessToken == "" {
+		return nil, TokenFetchError{Url: cc.TokenUrl, Err: errors.New(emptyTokenErrMsg)}
+	}
+
+	expiresIn := defaultTokenExpiry
+	if s, err := tr.ExpiresIn.Int64(); err == nil && s > 0 {
+		expiresIn = time.Duration(s) * time.Second
+	}
+
+	token := &Token{AccessToken: tr.AccessToken, TokenType: tr.TokenType, Expiry: time.Now().Add(expiresIn)}
+
+	log := LogFormatter{Msg: fmt.Sprintf(tokenFetchedMsg, cc.TokenUrl, token.Expiry)}
+	log.Debug().Println(log.Out)
+
+	return token, nil
+}
+
+// setAuthorization sets the Authorization header of the request based on the authentication information
+// A TokenSource takes precedence over a static Token, which takes precedence over basic authentication
+// Digest authentication is not set here as it requires a challenge from the remote server
+// The method returns an error if a token cannot be retrieved from the TokenSource
+func (a *Auth) setAuthorization(req *http.Request) error {
+	switch {
+	case a.TokenSource != nil:
+		token, err := a.TokenSource.Token(req.Context())
+		if err != nil {
+			return err
+		}
+		req.Header.Set(authorizationKey, token.AuthorizationHeader())
+	case a.Token != "":
+		req.Header.Set(authorizationKey, bearerTokenType+" "+a.Token)
+	case a.Username != "" && a.Password != "" && a.Scheme != DigestAuthScheme:
+		req.SetBasicAuth(a.Username, a.Password)
+	}
+	return nil
+}
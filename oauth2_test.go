@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func TestClientCredentials(t *testing.T) {
+	var fetched int32
+
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "client" || secret != "secret" || r.FormValue("grant_type") != clientCredentialsGrant {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		n := atomic.AddInt32(&fetched, 1)
+		w.Header().Set(contentTypeKey, applicationJsonContentType)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "token-" + strconv.Itoa(int(n)),
+			"token_type":   "bearer",
+			"expires_in":   3600,
+		})
+	}))
+	defer tokenServer.Close()
+
+	// the api server only accepts the second token to force a retry on 401
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(authorizationKey) != "Bearer token-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer apiServer.Close()
+
+	cc := &ClientCredentials{TokenUrl: tokenServer.URL, ClientId: "client", ClientSecret: "secret"}
+
+	r := Request{Url: apiServer.URL, Method: http.MethodPost, Body: RequestBody{Text: "body"}, Auth: Auth{TokenSource: cc}}
+	if err := r.NewRequest(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.HttpRequest(); err != nil {
+		t.Fatal(err)
+	}
+	if r.Result.StatusCode != http.StatusOK {
+		t.Errorf("Test Failed!, expected: %v, got: %v", http.StatusOK, r.Result.StatusCode)
+	}
+
+	r = Request{Url: apiServer.URL, Method: http.MethodGet, Auth: Auth{TokenSource: cc}}
+	if err := r.NewRequest(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.HttpRequest(); err != nil {
+		t.Fatal(err)
+	}
+
+	if fetched != 2 {
+		t.Errorf("Test Failed!, expected: %v, got: %v", 2, fetched)
+	}
+}
+
+func TestClientCredentialsFetchError(t *testing.T) {
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer tokenServer.Close()
+
+	r := Request{
+		Url:    tokenServer.URL,
+		Method: http.MethodGet,
+		Auth:   Auth{TokenSource: &ClientCredentials{TokenUrl: tokenServer.URL, ClientId: "client", ClientSecret: "invalid"}},
+	}
+	if err := r.NewRequest(); err != nil {
+		t.Fatal(err)
+	}
+
+	var tfe TokenFetchError
+	if err := r.HttpRequest(); !errors.As(err, &tfe) {
+		t.Errorf("Test Failed!, expected: %v, got: %v", "TokenFetchError", err)
+	}
+}
+
+func TestStaticBearerToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get(authorizationKey)))
+	}))
+	defer server.Close()
+
+	r := Request{Url: server.URL, Method: http.MethodGet, Auth: Auth{Token: "static"}}
+	if err := r.NewRequest(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.HttpRequest(); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(r.Result.Body) != "Bearer static" {
+		t.Errorf("Test Failed!, expected: %v, got: %v", "Bearer static", string(r.Result.Body))
+	}
+}
@@ -0,0 +1,362 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Pagination util constants
+const (
+	linkKey                 = "Link"
+	defaultPageParam        = "page"
+	defaultPageSizeParam    = "per_page"
+	defaultOffsetParam      = "offset"
+	defaultLimitParam       = "limit"
+	defaultCursorParam      = "cursor"
+	cursorFieldParam        = "CursorField"
+	jsonFieldNotFoundErrMsg = "JSON field '%s' was not found in the response"
+	pageFetchedMsg          = "Fetched page %d from %s with %d items"
+)
+
+// JSONFieldNotFoundError represents an error when a field is not found in a JSON document
+type JSONFieldNotFoundError string
+
+// Error returns the formatted JSONFieldNotFoundError
+func (jfn JSONFieldNotFoundError) Error() string {
+	return fmt.Sprintf(jsonFieldNotFoundErrMsg, string(jfn))
+}
+
+// Page represents a page fetched by a Paginator
+type Page struct {
+	Number int
+	Result Result
+	Items  []json.RawMessage
+}
+
+// PageStrategy prepares the requests for the pages of a paginated API
+// Next returns false when there are no more pages
+type PageStrategy interface {
+	First(r *Request)
+	Next(r *Request, page Page) (bool, error)
+}
+
+// setQuery sets a query parameter on the request
+func setQuery(r *Request, key, value string) {
+	query := make(map[string]string, len(r.Query)+1)
+	for k, v := range r.Query {
+		query[k] = v
+	}
+	query[key] = value
+	r.Query = query
+}
+
+// LinkHeaderPagination follows the url with rel="next" of the Link response header
+// The authentication of the request is removed when the next link points to another host and
+// the pagination stops when the next link points to the current page
+type LinkHeaderPagination struct{}
+
+// First does not modify the request
+func (lp LinkHeaderPagination) First(r *Request) {}
+
+// Next sets the url of the next page from the Link header
+func (lp LinkHeaderPagination) Next(r *Request, page Page) (bool, error) {
+	next := linkNext(page.Result.Header[linkKey])
+	if next == "" {
+		return false, nil
+	}
+	u, err := resolveUrl(r.Url, next)
+	if err != nil {
+		return false, err
+	}
+	if u == r.Url || (r.Request != nil && u == r.Request.URL.String()) {
+		return false, nil
+	}
+	// the credentials are not sent to a next link on another host
+	if !sameHost(r.Url, u) {
+		r.Auth = Auth{}
+	}
+	r.Url = u
+	// the next link contains all the query parameters
+	r.Query = nil
+	return true, nil
+}
+
+// linkNext returns the url with the relation type next from the values of the Link header
+// The method returns an empty string if there is no next link
+func linkNext(values []string) string {
+	for _, value := range values {
+		for _, link := range strings.Split(value, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range parts[1:] {
+				kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+				if len(kv) != 2 || !strings.EqualFold(kv[0], "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(kv[1], `"`)) {
+					if strings.EqualFold(rel, "next") {
+						return target[1 : len(target)-1]
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// sameHost returns true if both urls have the same host
+func sameHost(a, b string) bool {
+	ua, errA := url.Parse(a)
+	ub, errB := url.Parse(b)
+	return errA == nil && errB == nil && strings.EqualFold(ua.Host, ub.Host)
+}
+
+// resolveUrl resolves the reference against the base url
+func resolveUrl(base, ref string) (string, error) {
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", UrlParseError{Url: base, Err: err}
+	}
+	u, err := b.Parse(ref)
+	if err != nil {
+		return "", UrlParseError{Url: ref, Err: err}
+	}
+	return u.String(), nil
+}
+
+// PageNumberPagination increments a page number query parameter
+// The pagination stops when a page has less than PageSize items or no items when PageSize is not set
+// PageParam defaults to "page", SizeParam defaults to "per_page" and FirstPage defaults to 1
+type PageNumberPagination struct {
+	PageParam string
+	SizeParam string
+	PageSize  int
+	FirstPage int
+	page      int
+}
+
+// First sets the first page number and the page size
+func (pp *PageNumberPagination) First(r *Request) {
+	if pp.PageParam == "" {
+		pp.PageParam = defaultPageParam
+	}
+	if pp.SizeParam == "" {
+		pp.SizeParam = defaultPageSizeParam
+	}
+	pp.page = pp.FirstPage
+	if pp.page == 0 {
+		pp.page = 1
+	}
+	setQuery(r, pp.PageParam, strconv.Itoa(pp.page))
+	if pp.PageSize > 0 {
+		setQuery(r, pp.SizeParam, strconv.Itoa(pp.PageSize))
+	}
+}
+
+// Next sets the next page number
+func (pp *PageNumberPagination) Next(r *Request, page Page) (bool, error) {
+	if len(page.Items) == 0 || (pp.PageSize > 0 && len(page.Items) < pp.PageSize) {
+		return false, nil
+	}
+	pp.page++
+	setQuery(r, pp.PageParam, strconv.Itoa(pp.page))
+	return true, nil
+}
+
+// OffsetPagination increments an offset query parameter by the number of items received
+// The pagination stops when a page has less than Limit items or no items when Limit is not set
+// OffsetParam defaults to "offset" and LimitParam defaults to "limit"
+type OffsetPagination struct {
+	OffsetParam string
+	LimitParam  string
+	Limit       int
+	offset      int
+}
+
+// First sets the offset to zero and the limit
+func (op *OffsetPagination) First(r *Request) {
+	if op.OffsetParam == "" {
+		op.OffsetParam = defaultOffsetParam
+	}
+	if op.LimitParam == "" {
+		op.LimitParam = defaultLimitParam
+	}
+	op.offset = 0
+	setQuery(r, op.OffsetParam, "0")
+	if op.Limit > 0 {
+		setQuery(r, op.LimitParam, strconv.Itoa(op.Limit))
+	}
+}
+
+// Next sets the offset of the next page
+func (op *OffsetPagination) Next(r *Request, page Page) (bool, error) {
+	if len(page.Items) == 0 || (op.Limit > 0 && len(page.Items) < op.Limit) {
+		return false, nil
+	}
+	op.offset += len(page.Items)
+	setQuery(r, op.OffsetParam, strconv.Itoa(op.offset))
+	return true, nil
+}
+
+// CursorPagination reads the cursor of the next page from the CursorField of the JSON response
+// and sends it in the CursorParam query parameter, the pagination stops when the cursor is empty
+// CursorField is a mandatory dot separated path, for example "meta.next_cursor", and CursorParam defaults to "cursor"
+type CursorPagination struct {
+	CursorParam string
+	CursorField string
+}
+
+// First does not modify the request
+func (cp *CursorPagination) First(r *Request) {
+	if cp.CursorParam == "" {
+		cp.CursorParam = defaultCursorParam
+	}
+}
+
+// Next sets the cursor of the next page
+func (cp *CursorPagination) Next(r *Request, page Page) (bool, error) {
+	if strings.TrimSpace(cp.CursorField) == "" {
+		return false, MissingMandatoryParamError([]string{cursorFieldParam})
+	}
+	raw, err := jsonField(page.Result.Body, cp.CursorField)
+	if err != nil {
+		if _, ok := err.(JSONFieldNotFoundError); ok {
+			return false, nil
+		}
+		return false, err
+	}
+
+	var cursor interface{}
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return false, JSONUnMarshalError{Err: err}
+	}
+
+	var value string
+	switch c := cursor.(type) {
+	case nil:
+		return false, nil
+	case string:
+		value = c
+	default:
+		value = strings.TrimSpace(string(raw))
+	}
+	if value == "" {
+		return false, nil
+	}
+
+	setQuery(r, cp.CursorParam, value)
+	return true, nil
+}
+
+// jsonField returns the raw value of the dot separated field path in the JSON document
+// An empty path returns the whole document
+func jsonField(data []byte, path string) (json.RawMessage, error) {
+	raw := json.RawMessage(data)
+	if path == "" {
+		return raw, nil
+	}
+	for _, key := range strings.Split(path, ".") {
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return nil, JSONUnMarshalError{Err: err}
+		}
+		value, ok := obj[key]
+		if !ok {
+			return nil, JSONFieldNotFoundError(path)
+		}
+		raw = value
+	}
+	return raw, nil
+}
+
+// Paginator fetches all the pages of a paginated JSON API using the Strategy
+// Request is used as the template for the requests, ItemsField is the dot separated path to the array
+// of items in the JSON response and is empty if the response is the array itself
+// MaxPages limits the number of pages which are fetched, zero means no limit
+type Paginator struct {
+	Request    Request
+	Strategy   PageStrategy
+	ItemsField string
+	MaxPages   int
+}
+
+// EachPage calls fn for every page until there are no more pages
+// The method stops and returns the error if a request fails, the remote server responds with a
+// non 2xx status code, fn returns an error or the context of the request is done
+func (p *Paginator) EachPage(fn func(page Page) error) error {
+	r := p.Request
+	if r.Method == "" {
+		r.Method = http.MethodGet
+	}
+	p.Strategy.First(&r)
+
+	for number := 1; p.MaxPages == 0 || number <= p.MaxPages; number++ {
+		if r.Ctx != nil {
+			if err := r.Ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		if err := r.NewRequest(); err != nil {
+			return err
+		}
+		r.Request.Header.Set(acceptKey, applicationJsonContentType)
+		if err := r.HttpRequest(); err != nil {
+			return err
+		}
+		if err := r.Result.StatusError(); err != nil {
+			return err
+		}
+
+		raw, err := jsonField(r.Result.Body, p.ItemsField)
+		if err != nil {
+			return err
+		}
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return JSONUnMarshalError{Err: err}
+		}
+
+		page := Page{Number: number, Result: r.Result, Items: items}
+
+		log := LogFormatter{Msg: fmt.Sprintf(pageFetchedMsg, number, r.Request.URL, len(items))}
+		log.Debug().Println(log.Out)
+
+		if err := fn(page); err != nil {
+			return err
+		}
+
+		more, err := p.Strategy.Next(&r, page)
+		if err != nil || !more {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Each calls fn with the raw JSON of every item of every page until there are no more pages
+// The method stops and returns the error if fetching a page fails, fn returns an error
+// or the context of the request is done
+func (p *Paginator) Each(fn func(item json.RawMessage) error) error {
+	return p.EachPage(func(page Page) error {
+		for _, item := range page.Items {
+			if p.Request.Ctx != nil {
+				if err := p.Request.Ctx.Err(); err != nil {
+					return err
+				}
+			}
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func newPaginationTestServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+			page, _ = strconv.Atoi(cursor)
+		}
+		if page == 0 {
+			page = 1
+		}
+
+		items := []int{}
+		if page <= 3 {
+			items = []int{page*10 + 1, page*10 + 2}
+		}
+
+		switch r.URL.Path {
+		case "/link":
+			if page < 3 {
+				w.Header().Set(linkKey, fmt.Sprintf(`</link?page=%d>; rel="next", </link?page=3>; rel="last"`, page+1))
+			}
+			_ = json.NewEncoder(w).Encode(items)
+		case "/cursor":
+			next := ""
+			if page < 3 {
+				next = strconv.Itoa(page + 1)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": items,
+				"meta": map[string]string{"next": next},
+			})
+		case "/page":
+			_ = json.NewEncoder(w).Encode(items)
+		}
+	}))
+}
+
+func TestPaginator(t *testing.T) {
+	server := newPaginationTestServer()
+	defer server.Close()
+
+	tests := []struct {
+		path       string
+		strategy   PageStrategy
+		itemsField string
+	}{
+		{"/link", LinkHeaderPagination{}, ""},
+		{"/cursor", &CursorPagination{CursorField: "meta.next"}, "data"},
+		{"/page", &PageNumberPagination{PageSize: 2}, ""},
+	}
+
+	expected := []int{11, 12, 21, 22, 31, 32}
+
+	for _, test := range tests {
+		p := Paginator{
+			Request:    Request{Url: server.URL + test.path},
+			Strategy:   test.strategy,
+			ItemsField: test.itemsField,
+		}
+
+		var result []int
+		err := p.Each(func(item json.RawMessage) error {
+			var v int
+			if err := json.Unmarshal(item, &v); err != nil {
+				return err
+			}
+			result = append(result, v)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if fmt.Sprint(result) != fmt.Sprint(expected) {
+			t.Errorf("Test Failed! %s, expected: %v, got: %v", test.path, expected, result)
+		}
+	}
+}
+
+func TestPaginatorCancel(t *testing.T) {
+	server := newPaginationTestServer()
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p := Paginator{Request: Request{Url: server.URL + "/link", Ctx: ctx}, Strategy: LinkHeaderPagination{}}
+
+	count := 0
+	err := p.Each(func(item json.RawMessage) error {
+		count++
+		cancel()
+		return nil
+	})
+
+	if err != context.Canceled || count != 1 {
+		t.Errorf("Test Failed!, expected: %v after %v item, got: %v after %v items", context.Canceled, 1, err, count)
+	}
+}
+
+func TestLinkHeaderPaginationOtherHost(t *testing.T) {
+	r := Request{Url: "https://api.example.com/items", Auth: Auth{Token: "secret"}}
+	page := Page{Result: Result{Header: http.Header{linkKey: {`<https://api.example.com/items?page=2>; rel="next"`}}}}
+	if _, err := (LinkHeaderPagination{}).Next(&r, page); err != nil || r.Auth.Token != "secret" {
+		t.Errorf("Test Failed!, expected the credentials to be kept, got: %v %v", r.Auth, err)
+	}
+
+	page.Result.Header.Set(linkKey, `<https://other.example.com/items?page=3>; rel="next"`)
+	if _, err := (LinkHeaderPagination{}).Next(&r, page); err != nil || r.Auth.Token != "" {
+		t.Errorf("Test Failed!, expected the credentials to be removed, got: %v %v", r.Auth, err)
+	}
+}
+
+func TestLinkHeaderPaginationSelf(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set(linkKey, `</items?page=1>; rel="next"`)
+		_ = json.NewEncoder(w).Encode([]int{1})
+	}))
+	defer server.Close()
+
+	p := Paginator{Request: Request{Url: server.URL + "/items?page=1", Method: http.MethodGet}, Strategy: LinkHeaderPagination{}}
+	var pages int
+	err := p.EachPage(func(page Page) error {
+		pages++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pages != 1 || requests != 1 {
+		t.Errorf("Test Failed!, expected: %v, got: %v pages and %v requests", 1, pages, requests)
+	}
+}
+
+func TestCursorPaginationMissingField(t *testing.T) {
+	r := Request{Url: "https://api.example.com/items"}
+	page := Page{Result: Result{Body: []byte(`"cursor"`)}}
+	next, err := (&CursorPagination{}).Next(&r, page)
+	if _, ok := err.(MissingMandatoryParamError); !ok || next {
+		t.Errorf("Test Failed!, expected: %v, got: %v %v", MissingMandatoryParamError{cursorFieldParam}, next, err)
+	}
+}
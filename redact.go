@@ -0,0 +1,223 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Redaction util constants
+const (
+	redactedValue         = "*****"
+	defaultMaxLogBodySize = 4096
+	maxPeekBodySize       = 1 << 20
+	truncatedBodyMsg      = "... (truncated, %d bytes logged)"
+	streamedBodyMsg       = "<streamed body, not logged>"
+)
+
+var (
+	// fieldsRegexes caches the compiled regexes of the redacted JSON fields
+	fieldsRegexes sync.Map
+)
+
+// RedactionPolicy represents the rules applied to requests and responses before they are logged
+// Headers and JSONFields are matched case insensitively, form fields are redacted using the JSONFields
+// Bodies are truncated to MaxBodySize bytes, a zero MaxBodySize disables the truncation and
+// a negative MaxBodySize disables the logging of bodies
+// The debug logging never reads more than MaxBodySize bytes, or 1 MiB if the truncation is disabled,
+// and does not read responses of unknown length
+type RedactionPolicy struct {
+	Headers     []string
+	JSONFields  []string
+	MaxBodySize int
+}
+
+// DefaultRedactionPolicy is the RedactionPolicy used by clients which do not set a RedactionPolicy
+var DefaultRedactionPolicy = RedactionPolicy{
+	Headers: []string{
+		"Authorization",
+		"Proxy-Authorization",
+		"Cookie",
+		"Set-Cookie",
+		"X-Api-Key",
+	},
+	JSONFields: []string{
+		"password",
+		"secret",
+		"token",
+		"access_token",
+		"refresh_token",
+		"id_token",
+		"client_secret",
+		"api_key",
+	},
+	MaxBodySize: defaultMaxLogBodySize,
+}
+
+// isRedactedHeader checks if the header must be redacted
+func (rp *RedactionPolicy) isRedactedHeader(name string) bool {
+	for _, h := range rp.Headers {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRedactedField checks if the field must be redacted
+func (rp *RedactionPolicy) isRedactedField(name string) bool {
+	for _, f := range rp.JSONFields {
+		if strings.EqualFold(f, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactHeaders returns a copy of the headers with the values of the redacted headers masked
+func (rp *RedactionPolicy) RedactHeaders(header http.Header) http.Header {
+	out := header.Clone()
+	for k, values := range out {
+		if rp.isRedactedHeader(k) {
+			for i := range values {
+				values[i] = redactedValue
+			}
+		}
+	}
+	return out
+}
+
+// fieldsRegex returns a regex matching the string values of the redacted JSON fields
+// A value which is cut off at the end of the body is matched as well
+// The regex is compiled once for every set of fields
+func (rp *RedactionPolicy) fieldsRegex() *regexp.Regexp {
+	if len(rp.JSONFields) == 0 {
+		return nil
+	}
+	fields := make([]string, len(rp.JSONFields))
+	for i, f := range rp.JSONFields {
+		fields[i] = regexp.QuoteMeta(f)
+	}
+	expr := `(?i)("(?:` + strings.Join(fields, "|") + `)"\s*:\s*)"(?:[^"\\]|\\.)*(?:"|\\?$)`
+	if re, ok := fieldsRegexes.Load(expr); ok {
+		return re.(*regexp.Regexp)
+	}
+	re, _ := fieldsRegexes.LoadOrStore(expr, regexp.MustCompile(expr))
+	return re.(*regexp.Regexp)
+}
+
+// redactForm masks the values of the redacted fields of a form body
+// Bodies which cannot be parsed, for example because they are cut off, are masked field by field
+func (rp *RedactionPolicy) redactForm(body string) string {
+	if values, err := url.ParseQuery(body); err == nil {
+		for k := range values {
+			if rp.isRedactedField(k) {
+				values.Set(k, redactedValue)
+			}
+		}
+		return values.Encode()
+	}
+
+	pairs := strings.Split(body, "&")
+	for i, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		key, err := url.QueryUnescape(kv[0])
+		if err != nil {
+			key = kv[0]
+		}
+		if len(kv) == 2 && rp.isRedactedField(key) {
+			pairs[i] = kv[0] + "=" + url.QueryEscape(redactedValue)
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// RedactBody returns the body as a string which is safe to be logged
+// The values of the redacted fields of JSON and form bodies are masked before the body is truncated to MaxBodySize
+func (rp *RedactionPolicy) RedactBody(body []byte, contentType string) string {
+	return rp.redactBody(body, contentType, rp.MaxBodySize, false)
+}
+
+// redactBody masks the values of the redacted fields and truncates the body to limit bytes
+// A body which is already truncated is marked as truncated
+func (rp *RedactionPolicy) redactBody(body []byte, contentType string, limit int, truncated bool) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	out := string(body)
+	if mediaType == formContentType {
+		out = rp.redactForm(out)
+	} else if re := rp.fieldsRegex(); re != nil {
+		out = re.ReplaceAllString(out, `${1}"`+redactedValue+`"`)
+	}
+
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+		truncated = true
+	}
+	if truncated {
+		out += fmt.Sprintf(truncatedBodyMsg, len(out))
+	}
+	return out
+}
+
+// peekLimit returns the maximum number of bytes of a body which are logged
+func (rp *RedactionPolicy) peekLimit() int {
+	if rp.MaxBodySize > 0 {
+		return rp.MaxBodySize
+	}
+	return maxPeekBodySize
+}
+
+// peekBody reads at most one byte more than the peek limit so that truncation can be detected
+// The returned string is redacted and marked as truncated if the body is longer than the limit
+func (rp *RedactionPolicy) peekBody(r io.Reader, contentType string) ([]byte, string) {
+	limit := rp.peekLimit()
+	data, _ := ioutil.ReadAll(io.LimitReader(r, int64(limit)+1))
+	return data, rp.redactBody(data, contentType, limit, len(data) > limit)
+}
+
+// peekRequestBody returns the beginning of the request body without consuming the body
+// Bodies which cannot be replayed are not read
+func (rp *RedactionPolicy) peekRequestBody(req *http.Request) string {
+	if req.Body == nil || req.Body == http.NoBody {
+		return ""
+	}
+	if req.GetBody == nil {
+		return streamedBodyMsg
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return streamedBodyMsg
+	}
+	defer body.Close()
+
+	_, out := rp.peekBody(body, req.Header.Get(contentTypeKey))
+	return out
+}
+
+// peekResponseBody reads the beginning of the response body and returns it
+// The response body is replaced by a reader which returns the whole body
+// Responses of unknown length are not read, they may be streamed and reading them would block the request
+func (rp *RedactionPolicy) peekResponseBody(resp *http.Response) string {
+	if resp.Body == nil || resp.Body == http.NoBody {
+		return ""
+	}
+	if resp.ContentLength < 0 {
+		return streamedBodyMsg
+	}
+
+	data, out := rp.peekBody(resp.Body, resp.Header.Get(contentTypeKey))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+
+	return out
+}
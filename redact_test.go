@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRedactHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer secret")
+	header.Set("Accept", "application/json")
+
+	result := DefaultRedactionPolicy.RedactHeaders(header)
+
+	if result.Get("Authorization") != redactedValue {
+		t.Errorf("Test Failed!, expected: %v, got: %v", redactedValue, result.Get("Authorization"))
+	}
+	if result.Get("Accept") != "application/json" {
+		t.Errorf("Test Failed!, expected: %v, got: %v", "application/json", result.Get("Accept"))
+	}
+	if header.Get("Authorization") != "Bearer secret" {
+		t.Errorf("Test Failed!, expected the original headers not to be modified")
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	policy := RedactionPolicy{JSONFields: []string{"password", "token"}, MaxBodySize: 64}
+
+	result := policy.RedactBody([]byte(`{"user":"admin","Password":"p\"ss","nested":{"token": "abc"}}`), applicationJsonContentType)
+	expected := `{"user":"admin","Password":"*****","nested":{"token": "*****"}}`
+	if result != expected {
+		t.Errorf("Test Failed!, expected: %v, got: %v", expected, result)
+	}
+
+	result = policy.RedactBody([]byte("user=admin&password=secret"), formContentType)
+	expected = "password=%2A%2A%2A%2A%2A&user=admin"
+	if result != expected {
+		t.Errorf("Test Failed!, expected: %v, got: %v", expected, result)
+	}
+
+	policy.MaxBodySize = 4
+	result = policy.RedactBody([]byte("0123456789"), textPlainContentType)
+	expected = "0123... (truncated, 4 bytes logged)"
+	if result != expected {
+		t.Errorf("Test Failed!, expected: %v, got: %v", expected, result)
+	}
+}
+
+func TestRedactTruncatedBody(t *testing.T) {
+	policy := RedactionPolicy{JSONFields: []string{"password"}, MaxBodySize: 24}
+
+	tests := []struct {
+		body        string
+		contentType string
+		expected    string
+	}{
+		{"user=administrator&password=secret", formContentType, "password=%2A%2A%2A%2A%2A... (truncated, 24 bytes logged)"},
+		{"password=secret&user=admin%2", formContentType, "password=%2A%2A%2A%2A%2A... (truncated, 24 bytes logged)"},
+		{`{"user":"admin","password":"secret"}`, applicationJsonContentType, `{"user":"admin","passwor... (truncated, 24 bytes logged)`},
+		{`{"password":"supersecretsupersecret"}`, applicationJsonContentType, `{"password":"*****"}`},
+		{`{"password":"supersecret`, textPlainContentType, `{"password":"*****"`},
+	}
+
+	for _, test := range tests {
+		result := policy.RedactBody([]byte(test.body), test.contentType)
+		if result != test.expected {
+			t.Errorf("Test Failed!, expected: %v, got: %v", test.expected, result)
+		}
+		if strings.Contains(result, "secret") {
+			t.Errorf("Test Failed!, secret logged: %v", result)
+		}
+	}
+
+	// only the beginning of the response body is read, the truncated text is redacted
+	for _, body := range []string{`{"user":"administrator","password":"secret"}`, `{"user":"a","password":"secret"}`} {
+		resp := &http.Response{
+			Header:        http.Header{contentTypeKey: {applicationJsonContentType}},
+			Body:          ioutil.NopCloser(strings.NewReader(body)),
+			ContentLength: int64(len(body)),
+		}
+		result := policy.peekResponseBody(resp)
+		if strings.Contains(result, "secret") || !strings.HasSuffix(result, "bytes logged)") {
+			t.Errorf("Test Failed!, expected a redacted and truncated body, got: %v", result)
+		}
+		if data, _ := ioutil.ReadAll(resp.Body); string(data) != body {
+			t.Errorf("Test Failed!, expected: %v, got: %s", body, data)
+		}
+	}
+}
+
+func TestPeekStreamedResponseBody(t *testing.T) {
+	// the body is never written, reading it would block
+	pr, pw := io.Pipe()
+	defer pw.Close()
+
+	resp := &http.Response{Header: http.Header{}, Body: pr, ContentLength: -1}
+	if result := DefaultRedactionPolicy.peekResponseBody(resp); result != streamedBodyMsg {
+		t.Errorf("Test Failed!, expected: %v, got: %v", streamedBodyMsg, result)
+	}
+	if resp.Body != pr {
+		t.Errorf("Test Failed!, expected the response body not to be replaced")
+	}
+}
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Request id util constants
+const (
+	RequestIDKey    = "request_id"
+	RequestIDHeader = "X-Request-ID"
+
+	maxRequestIDLength = 128
+)
+
+// requestIDContextKey is the key of the request id in a context.Context
+type requestIDContextKey struct{}
+
+// ContextWithRequestID returns a copy of the context which carries the request id
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, id)
+}
+
+// RequestIDFromContext returns the request id carried by the context or an empty string
+// Both the context of a http request and a gin context are supported
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if id, ok := ctx.Value(requestIDContextKey{}).(string); ok {
+		return id
+	}
+	if id, ok := ctx.Value(RequestIDKey).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// newRequestID returns a new random request id
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID returns true if the request id received from a client can be used
+// Only printable ASCII characters are accepted to keep the ids safe for the logs and response headers
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// RequestID is a gin middleware which accepts the X-Request-ID header of the request or generates a new id
+// The id is stored in the gin context and in the context of the request, echoed in the X-Request-ID
+// response header and included in the LogFormatter output for the request
+// Requests made with the http client using the context of the request forward the id to the remote server
+func RequestID() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		ctx.Set(RequestIDKey, id)
+		ctx.Request = ctx.Request.WithContext(ContextWithRequestID(ctx.Request.Context(), id))
+		ctx.Header(RequestIDHeader, id)
+
+		ctx.Next()
+	}
+}
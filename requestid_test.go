@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestID(t *testing.T) {
+	var forwarded string
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Get(RequestIDHeader)
+	}))
+	defer downstream.Close()
+
+	gin.SetMode(gin.ReleaseMode)
+	engine := gin.New()
+	engine.Use(RequestID())
+	engine.GET("/proxy", func(ctx *gin.Context) {
+		r := Request{Url: downstream.URL, Method: http.MethodGet, Ctx: ctx}
+		if err := r.NewRequest(); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.HttpRequest(); err != nil {
+			t.Fatal(err)
+		}
+		log := LogFormatter{Request: ctx.Request, StatusCode: http.StatusOK, Msg: "proxied"}
+		ctx.String(http.StatusOK, log.GetLogMsg())
+	})
+
+	tests := []struct {
+		header   string
+		generate bool
+	}{
+		{"abc-123", false},
+		{"", true},
+		{"invalid id", true},
+		{strings.Repeat("a", maxRequestIDLength+1), true},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/proxy", nil)
+		if test.header != "" {
+			req.Header.Set(RequestIDHeader, test.header)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+
+		id := w.Header().Get(RequestIDHeader)
+		if test.generate && (id == test.header || len(id) != 32) {
+			t.Errorf("Test Failed!, expected a generated id for %q, got: %v", test.header, id)
+		}
+		if !test.generate && id != test.header {
+			t.Errorf("Test Failed!, expected: %v, got: %v", test.header, id)
+		}
+		if forwarded != id {
+			t.Errorf("Test Failed!, expected the id %v to be forwarded, got: %v", id, forwarded)
+		}
+		if !strings.HasSuffix(w.Body.String(), "request_id="+id) {
+			t.Errorf("Test Failed!, expected the id %v in the log message, got: %v", id, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Server util constants
+const (
+	defaultShutdownTimeout = 30 * time.Second
+
+	receivedSignalMsg     = "Received signal '%s'"
+	shutdownHookErrMsg    = "Shutdown hook failed"
+	serverListenErrMsg    = "Unable to listen on '%s' : %v"
+	serverShutdownErrMsg  = "Unable to shutdown the API server gracefully : %v"
+	serverShutdownDoneMsg = "All in-flight requests were completed"
+	serverDrainMsg        = "The service is not ready, waiting %v for the load balancers to stop sending requests"
+)
+
+// ServerListenError represents an error when the server cannot listen on its address
+type ServerListenError struct {
+	Addr string
+	Err  error
+}
+
+// Error returns the formatted ServerListenError
+func (sl ServerListenError) Error() string {
+	return fmt.Sprintf(serverListenErrMsg, sl.Addr, sl.Err)
+}
+
+// ServerShutdownError represents an error when the server cannot be shutdown gracefully
+type ServerShutdownError struct {
+	Err error
+}
+
+// Error returns the formatted ServerShutdownError
+func (ss ServerShutdownError) Error() string {
+	return fmt.Sprintf(serverShutdownErrMsg, ss.Err)
+}
+
+// ShutdownHook is called when the server is shut down after the in-flight requests are completed
+type ShutdownHook func(ctx context.Context) error
+
+// Server represents an API server which serves the gin Engine
+// The routes and middlewares are registered on the Engine before the server is run
+// Health serves /healthz and /readyz, the service is marked as not ready when the server is shut down
+// Metrics is the DefaultRegistry which records the requests, it is served on /metrics if the metrics endpoint is enabled
+// RateLimit is the rate limit middleware of the configuration, it is nil if the rate limit is not enabled
+type Server struct {
+	Engine    *gin.Engine
+	Cnf       ServerCnf
+	Health    *Health
+	Metrics   *Registry
+	RateLimit gin.HandlerFunc
+
+	httpServer *http.Server
+	listener   net.Listener
+	hooks      []ShutdownHook
+	stop       chan struct{}
+	stopOnce   sync.Once
+	mu         sync.Mutex
+}
+
+// NewServer validates the configuration, applies the logging and http settings and returns a new Server
+// Gin runs in debug mode when the log level is DEBUG and in release mode otherwise
+// The engine assigns a request id, logs and records metrics for every request, recovers from panics and responds with ErrResponse
+// bodies for unknown paths and methods, cross-origin requests are handled if CORS is enabled and
+// the requests are rate limited per client if the rate limit is enabled, except for the health and metrics endpoints
+// The username is only known after the authentication, with the "user" rate limit key the rate limit is therefore not
+// registered on the engine, Server.RateLimit must be registered after the authentication middleware of the route groups
+// The metrics endpoint is only registered if it is enabled
+// The method returns an error if the configuration is not valid or the metrics cannot be registered
+func NewServer(cnf ServerCnf) (*Server, error) {
+	if err := cnf.Validate(); err != nil {
+		return nil, err
+	}
+	cnf.Set()
+
+	if LogLevel == debugLogLevel {
+		gin.SetMode(gin.DebugMode)
+	} else {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	if cnf.ShutdownTimeout <= 0 {
+		cnf.ShutdownTimeout = defaultShutdownTimeout
+	}
+
+	metrics, err := RequestMetrics(DefaultRegistry)
+	if err != nil {
+		return nil, err
+	}
+
+	engine := gin.New()
+	engine.HandleMethodNotAllowed = true
+	engine.NoRoute(NoRouteHandler())
+	engine.NoMethod(NoMethodHandler())
+	engine.Use(RequestID(), AccessLog(cnf.AccessLogCnf), metrics, Recovery())
+
+	if cnf.CORSCnf.Enable {
+		cors, err := CORS(cnf.CORSCnf)
+		if err != nil {
+			return nil, err
+		}
+		engine.Use(cors)
+	}
+
+	var rateLimit gin.HandlerFunc
+	if cnf.RateLimitCnf.Enable {
+		rateLimitCnf := cnf.RateLimitCnf
+		rateLimitCnf.SkipPaths = append([]string{HealthzPath, ReadyzPath, cnf.MetricsCnf.path()}, rateLimitCnf.SkipPaths...)
+		rateLimit, err = RateLimit(rateLimitCnf)
+		if err != nil {
+			return nil, err
+		}
+		if rateLimitCnf.Key != RateLimitByUser {
+			engine.Use(rateLimit)
+		}
+	}
+
+	health := NewHealth()
+	health.Register(engine)
+	if cnf.MetricsCnf.Enable {
+		engine.GET(cnf.MetricsCnf.path(), MetricsHandler(DefaultRegistry))
+	}
+
+	return &Server{
+		Engine:     engine,
+		Cnf:        cnf,
+		Health:     health,
+		Metrics:    DefaultRegistry,
+		RateLimit:  rateLimit,
+		httpServer: &http.Server{Addr: net.JoinHostPort(cnf.Host, cnf.Port), Handler: engine},
+		stop:       make(chan struct{}),
+	}, nil
+}
+
+// OnShutdown registers hooks which are called in order when the server is shut down
+func (s *Server) OnShutdown(hooks ...ShutdownHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, hooks...)
+}
+
+// Addr returns the address the server is listening on or an empty string if the server is not listening yet
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Stop shuts down the server as if it received a SIGTERM
+func (s *Server) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
+
+// Run starts the server and blocks until the server receives SIGINT or SIGTERM or Stop is called
+// The service is then marked as not ready and after the DrainDelay the listeners are closed and the
+// in-flight requests are given ShutdownTimeout to complete after which the shutdown hooks are called
+// The method returns an error if the server cannot listen on its address, fails while serving or
+// cannot be shut down gracefully
+func (s *Server) Run() error {
+	log := LogFormatter{Msg: StartingServerMsg}
+	log.Info().Println(log.Out)
+
+	ln, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return ServerListenError{Addr: s.httpServer.Addr, Err: err}
+	}
+	s.mu.Lock()
+	s.listener = ln
+	s.mu.Unlock()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if s.Cnf.TLSCnf.Enable {
+			serveErr <- s.httpServer.ServeTLS(ln, s.Cnf.TLSCnf.CertFile, s.Cnf.TLSCnf.KeyFile)
+		} else {
+			serveErr <- s.httpServer.Serve(ln)
+		}
+	}()
+
+	log = LogFormatter{Msg: fmt.Sprintf(StartedServerMsg, ln.Addr())}
+	log.Info().Println(log.Out)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case err := <-serveErr:
+		if err != http.ErrServerClosed {
+			return err
+		}
+	case sig := <-signals:
+		log = LogFormatter{Msg: fmt.Sprintf(receivedSignalMsg, sig)}
+		log.Info().Println(log.Out)
+	case <-s.stop:
+	}
+
+	return s.shutdown()
+}
+
+// shutdown marks the service as not ready, drains the in-flight requests and calls the shutdown hooks
+func (s *Server) shutdown() error {
+	log := LogFormatter{Msg: StoppingServerMsg}
+	log.Info().Println(log.Out)
+
+	s.Health.SetReady(false)
+	if s.Cnf.DrainDelay > 0 {
+		log = LogFormatter{Msg: fmt.Sprintf(serverDrainMsg, s.Cnf.DrainDelay)}
+		log.Info().Println(log.Out)
+		time.Sleep(s.Cnf.DrainDelay)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.Cnf.ShutdownTimeout)
+	defer cancel()
+
+	var shutdownErr error
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		shutdownErr = ServerShutdownError{Err: err}
+		log = LogFormatter{ErrMsg: shutdownErr}
+		log.Error().Println(log.Out)
+	} else {
+		log = LogFormatter{Msg: serverShutdownDoneMsg}
+		log.Debug().Println(log.Out)
+	}
+
+	s.mu.Lock()
+	hooks := s.hooks
+	s.mu.Unlock()
+
+	for _, hook := range hooks {
+		if err := hook(ctx); err != nil {
+			log = LogFormatter{Msg: shutdownHookErrMsg, ErrMsg: err}
+			log.Error().Println(log.Out)
+			if shutdownErr == nil {
+				shutdownErr = err
+			}
+		}
+	}
+
+	log = LogFormatter{Msg: StoppedServerMsg}
+	log.Info().Println(log.Out)
+
+	return shutdownErr
+}
@@ -0,0 +1,286 @@
+package utils
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Server rate limit util constants
+const (
+	RateLimitByIP     = "ip"
+	RateLimitByUser   = "user"
+	RateLimitByAPIKey = "api_key"
+
+	cnfRateLimitRateKey      = "server.rate_limit.rate"
+	cnfRateLimitIPRateKey    = "server.rate_limit.ip_rate"
+	cnfRateLimitRoutePathKey = "server.rate_limit.routes.path"
+
+	defaultAPIKeyHeader      = "X-API-Key"
+	rateLimitLimitHeader     = "RateLimit-Limit"
+	rateLimitRemainingHeader = "RateLimit-Remaining"
+	rateLimitResetHeader     = "RateLimit-Reset"
+	bucketSweepInterval      = time.Minute
+	defaultMaxClients        = 10000
+	apiKeyIPBucketPrefix     = "api_key_ip|"
+
+	invalidRateLimitKeyErrMsg  = "Invalid rate limit key '%s'. Valid values are %v"
+	invalidServerRateLimitMsg  = "Invalid rate limit for '%s' : rate and burst must be greater than zero"
+	serverRateLimitExceededMsg = "Rate limit exceeded for '%s'"
+)
+
+var (
+	validRateLimitKeys = []string{RateLimitByIP, RateLimitByUser, RateLimitByAPIKey}
+)
+
+// InvalidRateLimitKeyError represents an error when the rate limit key is not valid
+type InvalidRateLimitKeyError string
+
+// Error returns the formatted InvalidRateLimitKeyError
+func (irk InvalidRateLimitKeyError) Error() string {
+	return fmt.Sprintf(invalidRateLimitKeyErrMsg, string(irk), validRateLimitKeys)
+}
+
+// InvalidServerRateLimitError represents an error when the rate or burst of a server rate limit is not valid
+type InvalidServerRateLimitError string
+
+// Error returns the formatted InvalidServerRateLimitError
+func (isr InvalidServerRateLimitError) Error() string {
+	return fmt.Sprintf(invalidServerRateLimitMsg, string(isr))
+}
+
+// RouteRateLimitCnf overrides the rate limit for a route
+// Path is the route as registered on the gin engine, for example "/users/:id", and Method is
+// optional, an empty Method applies the override to all the methods of the route
+type RouteRateLimitCnf struct {
+	Method string  `yaml:"method" mapstructure:"method"`
+	Path   string  `yaml:"path" mapstructure:"path"`
+	Rate   float64 `yaml:"rate" mapstructure:"rate"`
+	Burst  int     `yaml:"burst" mapstructure:"burst"`
+}
+
+// RateLimitCnf represents the server side rate limit configuration
+// Every client gets a token bucket which is refilled at Rate requests per second up to Burst requests
+// Clients are identified by Key, which is "ip" (default), "user" or "api_key", clients without a username
+// or API key are identified by their IP, the API key is read from the APIKeyHeader which defaults to X-API-Key
+// The API keys are not verified by the middleware, requests with an API key are therefore also limited per IP
+// at IPRate requests per second up to IPBurst requests, which default to Rate and Burst
+// Routes override the rate limit per route and SkipPaths are never rate limited
+// MaxClients is the number of clients which are tracked, defaults to 10000, the least recently seen
+// clients are forgotten when there are more clients
+// The username is only known if the middleware runs after the authentication middleware, NewServer therefore
+// leaves the registration of the "user" rate limit to the route groups, see Server.RateLimit
+type RateLimitCnf struct {
+	Enable       bool                `yaml:"enable" mapstructure:"enable"`
+	Key          string              `yaml:"key" mapstructure:"key"`
+	APIKeyHeader string              `yaml:"api_key_header" mapstructure:"api_key_header"`
+	Rate         float64             `yaml:"rate" mapstructure:"rate"`
+	Burst        int                 `yaml:"burst" mapstructure:"burst"`
+	IPRate       float64             `yaml:"ip_rate" mapstructure:"ip_rate"`
+	IPBurst      int                 `yaml:"ip_burst" mapstructure:"ip_burst"`
+	MaxClients   int                 `yaml:"max_clients" mapstructure:"max_clients"`
+	Routes       []RouteRateLimitCnf `yaml:"routes" mapstructure:"routes"`
+	SkipPaths    []string            `yaml:"skip_paths" mapstructure:"skip_paths"`
+}
+
+// Validate checks if the values in the RateLimitCnf are valid
+func (rc *RateLimitCnf) Validate() error {
+	if !rc.Enable {
+		return nil
+	}
+
+	if rc.Key != "" && !EntryExists(validRateLimitKeys, rc.Key) {
+		return InvalidRateLimitKeyError(rc.Key)
+	}
+	if rc.Rate <= 0 || rc.Burst <= 0 {
+		return InvalidServerRateLimitError(cnfRateLimitRateKey)
+	}
+	if rc.IPRate < 0 || rc.IPBurst < 0 {
+		return InvalidServerRateLimitError(cnfRateLimitIPRateKey)
+	}
+
+	for _, route := range rc.Routes {
+		if strings.TrimSpace(route.Path) == "" {
+			return MissingMandatoryParamError([]string{cnfRateLimitRoutePathKey})
+		}
+		if route.Rate <= 0 || route.Burst <= 0 {
+			return InvalidServerRateLimitError(strings.TrimSpace(route.Method + " " + route.Path))
+		}
+	}
+
+	return nil
+}
+
+// limit returns the rate, burst and bucket name which apply to the route
+func (rc *RateLimitCnf) limit(method, route string) (float64, int, string) {
+	for _, r := range rc.Routes {
+		if r.Path == route && (r.Method == "" || strings.EqualFold(r.Method, method)) {
+			return r.Rate, r.Burst, r.Method + " " + r.Path
+		}
+	}
+	return rc.Rate, rc.Burst, ""
+}
+
+// ipLimit returns the rate and burst of the per IP limit of the requests with an API key
+func (rc *RateLimitCnf) ipLimit() (float64, int) {
+	rate, burst := rc.IPRate, rc.IPBurst
+	if rate == 0 {
+		rate = rc.Rate
+	}
+	if burst == 0 {
+		burst = rc.Burst
+	}
+	return rate, burst
+}
+
+// clientKey returns the key which identifies the client of the request
+func (rc *RateLimitCnf) clientKey(ctx *gin.Context) string {
+	switch rc.Key {
+	case RateLimitByUser:
+		if user := ctx.GetString(UsernameKey); user != "" {
+			return RateLimitByUser + ":" + user
+		}
+	case RateLimitByAPIKey:
+		header := rc.APIKeyHeader
+		if header == "" {
+			header = defaultAPIKeyHeader
+		}
+		if key := ctx.GetHeader(header); key != "" {
+			// the API key is hashed so that it does not end up in the logs
+			return RateLimitByAPIKey + ":" + hashHex(sha256.New, key)[:16]
+		}
+	}
+	return RateLimitByIP + ":" + GetRequesterIP(ctx.Request)
+}
+
+// bucketEntry represents a token bucket of the bucketStore
+type bucketEntry struct {
+	key    string
+	bucket *TokenBucket
+}
+
+// bucketStore holds the token buckets of the clients, discards the idle buckets and keeps at most max buckets
+// by discarding the least recently used buckets
+type bucketStore struct {
+	buckets   map[string]*list.Element
+	lru       *list.List
+	max       int
+	lastSweep time.Time
+	mu        sync.Mutex
+}
+
+// newBucketStore returns a new bucketStore which keeps at most max buckets
+func newBucketStore(max int) *bucketStore {
+	if max <= 0 {
+		max = defaultMaxClients
+	}
+	return &bucketStore{buckets: make(map[string]*list.Element), lru: list.New(), max: max, lastSweep: time.Now()}
+}
+
+// get returns the bucket for the key, a new bucket is created if it does not exist
+func (bs *bucketStore) get(key string, rate float64, burst int) *TokenBucket {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(bs.lastSweep) > bucketSweepInterval {
+		for k, e := range bs.buckets {
+			if e.Value.(*bucketEntry).bucket.idle(now) {
+				bs.lru.Remove(e)
+				delete(bs.buckets, k)
+			}
+		}
+		bs.lastSweep = now
+	}
+
+	if e, ok := bs.buckets[key]; ok {
+		bs.lru.MoveToFront(e)
+		return e.Value.(*bucketEntry).bucket
+	}
+
+	b := NewTokenBucket(rate, burst)
+	bs.buckets[key] = bs.lru.PushFront(&bucketEntry{key: key, bucket: b})
+	for len(bs.buckets) > bs.max {
+		oldest := bs.lru.Back()
+		bs.lru.Remove(oldest)
+		delete(bs.buckets, oldest.Value.(*bucketEntry).key)
+	}
+	return b
+}
+
+// allow takes a token from the bucket of the client and sets the rate limit headers
+// The request is aborted with 429 Too Many Requests and the method returns false if the bucket is empty
+func allow(ctx *gin.Context, bucket *TokenBucket, rate float64, burst int, client string) bool {
+	wait := bucket.Reserve()
+	tokens := bucket.Tokens()
+
+	reset := wait
+	if wait == 0 {
+		reset = time.Duration((float64(burst) - tokens) / rate * float64(time.Second))
+	}
+	resetSeconds := strconv.Itoa(int(math.Ceil(reset.Seconds())))
+
+	ctx.Header(rateLimitLimitHeader, strconv.Itoa(burst))
+	ctx.Header(rateLimitRemainingHeader, strconv.Itoa(int(math.Max(0, math.Floor(tokens)))))
+	ctx.Header(rateLimitResetHeader, resetSeconds)
+
+	if wait > 0 {
+		ctx.Header(retryAfterKey, resetSeconds)
+		ctx.AbortWithStatusJSON(http.StatusTooManyRequests, ErrResponse{Error: TooManyRequestsMsg})
+		log := LogFormatter{Request: ctx.Request, StatusCode: http.StatusTooManyRequests, Msg: fmt.Sprintf(serverRateLimitExceededMsg, client)}
+		log.Debug().Println(log.Out)
+		return false
+	}
+	return true
+}
+
+// RateLimit returns a gin middleware which limits the rate of the requests per client
+// Responses include the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers and
+// requests exceeding the limit are rejected with 429 Too Many Requests and a Retry-After header
+// With the "user" key the middleware must be registered after the authentication middleware
+// The Enable flag of the configuration is ignored, the method returns an error if the configuration is not valid
+func RateLimit(cnf RateLimitCnf) (gin.HandlerFunc, error) {
+	cnf.Enable = true
+	if err := cnf.Validate(); err != nil {
+		return nil, err
+	}
+
+	skip := make(map[string]bool, len(cnf.SkipPaths))
+	for _, path := range cnf.SkipPaths {
+		skip[path] = true
+	}
+	store := newBucketStore(cnf.MaxClients)
+	ipRate, ipBurst := cnf.ipLimit()
+
+	return func(ctx *gin.Context) {
+		if skip[ctx.Request.URL.Path] {
+			ctx.Next()
+			return
+		}
+
+		rate, burst, name := cnf.limit(ctx.Request.Method, ctx.FullPath())
+		client := cnf.clientKey(ctx)
+
+		if strings.HasPrefix(client, RateLimitByAPIKey+":") {
+			// a client must not be able to reset its limit by sending another API key
+			ip := RateLimitByIP + ":" + GetRequesterIP(ctx.Request)
+			if !allow(ctx, store.get(apiKeyIPBucketPrefix+ip, ipRate, ipBurst), ipRate, ipBurst, ip) {
+				return
+			}
+		}
+
+		if !allow(ctx, store.get(name+"|"+client, rate, burst), rate, burst, client) {
+			return
+		}
+
+		ctx.Next()
+	}, nil
+}
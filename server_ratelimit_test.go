@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newRateLimitEngine returns a gin engine with the rate limit middleware and a few routes
+func newRateLimitEngine(t *testing.T, cnf RateLimitCnf) *gin.Engine {
+	limit, err := RateLimit(cnf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.ReleaseMode)
+	engine := gin.New()
+	engine.Use(limit)
+	for _, path := range []string{"/items", "/login", "/healthz"} {
+		engine.GET(path, func(ctx *gin.Context) {
+			ctx.String(http.StatusOK, "ok")
+		})
+	}
+	return engine
+}
+
+// serve makes a request from the remote address with the headers
+func serve(engine *gin.Engine, path, remoteAddr string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = remoteAddr
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit(t *testing.T) {
+	engine := newRateLimitEngine(t, RateLimitCnf{
+		Rate:      0.001,
+		Burst:     2,
+		Routes:    []RouteRateLimitCnf{{Method: http.MethodGet, Path: "/login", Rate: 0.001, Burst: 1}},
+		SkipPaths: []string{"/healthz"},
+	})
+
+	w := serve(engine, "/items", "10.0.0.1:1234", nil)
+	if w.Code != http.StatusOK || w.Header().Get(rateLimitLimitHeader) != "2" || w.Header().Get(rateLimitRemainingHeader) != "1" {
+		t.Errorf("Test Failed!, unexpected response: %v %v", w.Code, w.Header())
+	}
+	serve(engine, "/items", "10.0.0.1:1234", nil)
+
+	w = serve(engine, "/items", "10.0.0.1:1234", nil)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get(retryAfterKey) == "" || w.Header().Get(rateLimitRemainingHeader) != "0" {
+		t.Errorf("Test Failed!, expected 429, got: %v %v", w.Code, w.Header())
+	}
+	var resp ErrResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Error != TooManyRequestsMsg {
+		t.Errorf("Test Failed!, expected: %v, got: %v", TooManyRequestsMsg, w.Body.String())
+	}
+
+	// other clients and skipped paths are not affected
+	if w := serve(engine, "/items", "10.0.0.2:1234", nil); w.Code != http.StatusOK {
+		t.Errorf("Test Failed!, expected: %v, got: %v", http.StatusOK, w.Code)
+	}
+	if w := serve(engine, "/healthz", "10.0.0.1:1234", nil); w.Code != http.StatusOK {
+		t.Errorf("Test Failed!, expected: %v, got: %v", http.StatusOK, w.Code)
+	}
+
+	// the route override has its own bucket
+	if w := serve(engine, "/login", "10.0.0.1:1234", nil); w.Code != http.StatusOK || w.Header().Get(rateLimitLimitHeader) != "1" {
+		t.Errorf("Test Failed!, expected: %v, got: %v", http.StatusOK, w.Code)
+	}
+	if w := serve(engine, "/login", "10.0.0.1:1234", nil); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Test Failed!, expected: %v, got: %v", http.StatusTooManyRequests, w.Code)
+	}
+}
+
+func TestRateLimitByAPIKey(t *testing.T) {
+	engine := newRateLimitEngine(t, RateLimitCnf{Key: RateLimitByAPIKey, Rate: 0.001, Burst: 1, IPBurst: 2})
+
+	if w := serve(engine, "/items", "10.0.0.1:1234", map[string]string{defaultAPIKeyHeader: "key-1"}); w.Code != http.StatusOK {
+		t.Errorf("Test Failed!, expected: %v, got: %v", http.StatusOK, w.Code)
+	}
+	if w := serve(engine, "/items", "10.0.0.1:1234", map[string]string{defaultAPIKeyHeader: "key-2"}); w.Code != http.StatusOK {
+		t.Errorf("Test Failed!, expected: %v, got: %v", http.StatusOK, w.Code)
+	}
+	if w := serve(engine, "/items", "10.0.0.2:1234", map[string]string{defaultAPIKeyHeader: "key-1"}); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Test Failed!, expected: %v, got: %v", http.StatusTooManyRequests, w.Code)
+	}
+
+	// changing the API key does not reset the limit of the IP
+	if w := serve(engine, "/items", "10.0.0.1:1234", map[string]string{defaultAPIKeyHeader: "key-3"}); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Test Failed!, expected: %v, got: %v", http.StatusTooManyRequests, w.Code)
+	}
+}
+
+func TestBucketStoreMaxClients(t *testing.T) {
+	store := newBucketStore(2)
+	a := store.get("a", 1, 1)
+	store.get("b", 1, 1)
+	store.get("a", 1, 1)
+	store.get("c", 1, 1)
+
+	if len(store.buckets) != 2 || store.lru.Len() != 2 {
+		t.Errorf("Test Failed!, expected: %v buckets, got: %v", 2, len(store.buckets))
+	}
+	if _, ok := store.buckets["b"]; ok {
+		t.Error("Test Failed!, expected the least recently used bucket to be discarded")
+	}
+	if store.get("a", 1, 1) != a {
+		t.Error("Test Failed!, expected the recently used bucket to be kept")
+	}
+}
+
+func TestRateLimitCnfValidate(t *testing.T) {
+	cnfs := []RateLimitCnf{
+		{Enable: true, Rate: 1},
+		{Enable: true, Rate: 1, Burst: 1, Key: "session"},
+		{Enable: true, Rate: 1, Burst: 1, Routes: []RouteRateLimitCnf{{Rate: 1, Burst: 1}}},
+		{Enable: true, Rate: 1, Burst: 1, Routes: []RouteRateLimitCnf{{Path: "/login"}}},
+		{Enable: true, Rate: 1, Burst: 1, IPRate: -1},
+	}
+	for _, cnf := range cnfs {
+		if err := cnf.Validate(); err == nil {
+			t.Errorf("Test Failed!, expected an error for %+v", cnf)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// startServer runs the server in the background and waits until it is listening
+func startServer(t *testing.T, s *Server) (string, chan error) {
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run()
+	}()
+	for i := 0; i < 100; i++ {
+		if addr := s.Addr(); addr != "" {
+			return "http://" + addr, done
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Test Failed!, the server did not start")
+	return "", nil
+}
+
+func TestServerGracefulShutdown(t *testing.T) {
+	s, err := NewServer(ServerCnf{Host: "127.0.0.1", Port: "0", LoggerCnf: LoggerCnf{Level: infoLogLevel}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	s.Engine.GET("/slow", func(ctx *gin.Context) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		ctx.String(http.StatusOK, "done")
+	})
+
+	var hookCalled bool
+	s.OnShutdown(func(ctx context.Context) error {
+		hookCalled = true
+		return nil
+	})
+
+	url, done := startServer(t, s)
+
+	result := make(chan Request, 1)
+	go func() {
+		r := Request{Url: url + "/slow", Method: http.MethodGet}
+		if err := r.NewRequest(); err == nil {
+			_ = r.HttpRequest()
+		}
+		result <- r
+	}()
+
+	<-started
+	s.Stop()
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	r := <-result
+	if r.Result.StatusCode != http.StatusOK || string(r.Result.Body) != "done" {
+		t.Errorf("Test Failed!, expected the in-flight request to complete, got: %v %q", r.Result.StatusCode, r.Result.Body)
+	}
+	if !hookCalled {
+		t.Error("Test Failed!, expected the shutdown hook to be called")
+	}
+	if s.Health.IsReady() {
+		t.Error("Test Failed!, expected the service to be not ready after the shutdown")
+	}
+}
+
+func TestServerDrainDelay(t *testing.T) {
+	s, err := NewServer(ServerCnf{Host: "127.0.0.1", Port: "0", DrainDelay: 300 * time.Millisecond, LoggerCnf: LoggerCnf{Level: infoLogLevel}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	url, done := startServer(t, s)
+
+	s.Stop()
+	for s.Health.IsReady() {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the server keeps serving while the load balancers notice that the service is not ready
+	r := Request{Url: url + ReadyzPath, Method: http.MethodGet}
+	if err := r.NewRequest(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.HttpRequest(); err != nil {
+		t.Fatalf("Test Failed!, expected the server to be serving during the drain delay, got: %v", err)
+	}
+	if r.Result.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Test Failed!, expected: %v, got: %v", http.StatusServiceUnavailable, r.Result.StatusCode)
+	}
+
+	if err := <-done; err != nil {
+		t.Errorf("Test Failed!, expected no error, got: %v", err)
+	}
+}
+
+func TestNewServerInvalidCnf(t *testing.T) {
+	cnfs := []ServerCnf{
+		{Host: "127.0.0.1", LoggerCnf: LoggerCnf{Level: infoLogLevel}},
+		{Host: "127.0.0.1", Port: "0", LoggerCnf: LoggerCnf{Level: infoLogLevel}, TLSCnf: TLSCnf{Enable: true}},
+	}
+	for _, cnf := range cnfs {
+		if _, err := NewServer(cnf); err == nil {
+			t.Errorf("Test Failed!, expected an error for %+v", cnf)
+		} else if _, ok := err.(MissingMandatoryParamError); !ok {
+			t.Errorf("Test Failed!, expected: MissingMandatoryParamError, got: %T", err)
+		}
+	}
+}
+
+func TestNewServerRateLimit(t *testing.T) {
+	cnf := ServerCnf{Host: "127.0.0.1", Port: "0", LoggerCnf: LoggerCnf{Level: infoLogLevel},
+		RateLimitCnf: RateLimitCnf{Enable: true, Key: RateLimitByIP, Rate: 0.001, Burst: 1}}
+	s, err := NewServer(cnf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{HealthzPath, ReadyzPath, HealthzPath} {
+		if w := serve(s.Engine, path, "10.0.0.1:1234", nil); w.Code == http.StatusTooManyRequests {
+			t.Errorf("Test Failed!, expected %v not to be rate limited", path)
+		}
+	}
+}
+
+func TestNewServerUserRateLimit(t *testing.T) {
+	cnf := ServerCnf{Host: "127.0.0.1", Port: "0", LoggerCnf: LoggerCnf{Level: infoLogLevel},
+		RateLimitCnf: RateLimitCnf{Enable: true, Key: RateLimitByUser, Rate: 0.001, Burst: 1}}
+	s, err := NewServer(cnf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the rate limit is registered after the authentication of the group
+	s.Engine.GET("/public", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	users := s.Engine.Group("/users", func(ctx *gin.Context) {
+		ctx.Set(UsernameKey, ctx.GetHeader("X-User"))
+	}, s.RateLimit)
+	users.GET("/me", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	tests := []struct {
+		path     string
+		user     string
+		expected int
+	}{
+		{"/public", "", http.StatusOK},
+		{"/public", "", http.StatusOK},
+		{"/users/me", "alice", http.StatusOK},
+		{"/users/me", "alice", http.StatusTooManyRequests},
+		{"/users/me", "bob", http.StatusOK},
+	}
+	for _, test := range tests {
+		w := serve(s.Engine, test.path, "10.0.0.1:1234", map[string]string{"X-User": test.user})
+		if w.Code != test.expected {
+			t.Errorf("Test Failed!, %v %v expected: %v, got: %v", test.path, test.user, test.expected, w.Code)
+		}
+	}
+}
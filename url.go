@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// URL util constants
+const (
+	urlParseErrMsg = "Unable to parse URL '%s' : %v"
+)
+
+// UrlParseError represents an error when a url cannot be parsed
+type UrlParseError struct {
+	Url string
+	Err error
+}
+
+// Error returns the formatted UrlParseError
+func (up UrlParseError) Error() string {
+	return fmt.Sprintf(urlParseErrMsg, up.Url, up.Err)
+}
+
+// UrlBuilder builds a URL from a base URL, path segments and query parameters
+// Every path segment and query value is escaped, so segments may contain characters like '/' or '?'
+type UrlBuilder struct {
+	base     string
+	segments []string
+	query    url.Values
+}
+
+// NewUrlBuilder returns a new UrlBuilder for the base URL
+func NewUrlBuilder(base string) *UrlBuilder {
+	return &UrlBuilder{base: base, query: url.Values{}}
+}
+
+// Path appends the path segments to the URL
+func (ub *UrlBuilder) Path(segments ...string) *UrlBuilder {
+	ub.segments = append(ub.segments, segments...)
+	return ub
+}
+
+// Query adds the query parameter to the URL
+func (ub *UrlBuilder) Query(key, value string) *UrlBuilder {
+	ub.query.Add(key, value)
+	return ub
+}
+
+// QueryParams adds all the query parameters to the URL
+func (ub *UrlBuilder) QueryParams(params map[string]string) *UrlBuilder {
+	for k, v := range params {
+		ub.query.Add(k, v)
+	}
+	return ub
+}
+
+// Build returns the formatted URL
+// The method returns an error if the base URL cannot be parsed
+func (ub *UrlBuilder) Build() (string, error) {
+	u, err := url.Parse(ub.base)
+	if err != nil {
+		return "", UrlParseError{Url: ub.base, Err: err}
+	}
+
+	if len(ub.segments) > 0 {
+		path := strings.TrimSuffix(u.Path, "/")
+		rawPath := strings.TrimSuffix(u.EscapedPath(), "/")
+		for _, s := range ub.segments {
+			path += "/" + s
+			rawPath += "/" + url.PathEscape(s)
+		}
+		u.Path = path
+		u.RawPath = rawPath
+	}
+
+	if len(ub.query) > 0 {
+		q := u.Query()
+		for k, values := range ub.query {
+			for _, v := range values {
+				q.Add(k, v)
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	return u.String(), nil
+}
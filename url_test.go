@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUrlBuilder(t *testing.T) {
+	result, err := NewUrlBuilder("https://example.com/api/?page=1").
+		Path("repos", "group/name", "a b").
+		Query("q", "x&y=z").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "https://example.com/api/repos/group%2Fname/a%20b?page=1&q=x%26y%3Dz"
+	if result != expected {
+		t.Errorf("Test Failed!, expected: %v, got: %v", expected, result)
+	}
+
+	if _, err := NewUrlBuilder("://invalid").Build(); err == nil {
+		t.Errorf("Test Failed!, expected: %v, got: %v", "UrlParseError", err)
+	}
+}
+
+func ExampleUrlBuilder() {
+	u, _ := NewUrlBuilder("https://example.com").Path("users", "john doe").Query("active", "true").Build()
+	fmt.Println(u)
+	// Output: https://example.com/users/john%20doe?active=true
+}
+
+func TestRequestHeadersQueryCookies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+			return
+		}
+		session, _ := r.Cookie("session")
+		_, _ = fmt.Fprintf(w, "%s|%s|%s", r.Header.Get("X-Custom"), r.URL.Query().Get("q"), session.Value)
+	}))
+	defer server.Close()
+
+	client := &Client{}
+	client.EnableCookieJar()
+
+	r := Request{Url: server.URL + "/login", Method: http.MethodGet, Client: client}
+	if err := r.NewRequest(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.HttpRequest(); err != nil {
+		t.Fatal(err)
+	}
+
+	r = Request{
+		Url:     server.URL + "/data",
+		Method:  http.MethodGet,
+		Headers: map[string]string{"X-Custom": "custom"},
+		Query:   map[string]string{"q": "a b"},
+		Client:  client,
+	}
+	if err := r.NewRequest(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.HttpRequest(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "custom|a b|abc"
+	if string(r.Result.Body) != expected {
+		t.Errorf("Test Failed!, expected: %v, got: %v", expected, string(r.Result.Body))
+	}
+}